@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// decodeCharset transcodes data in the given charset to UTF-8. Labels are
+// looked up in the WHATWG table browsers use, so ISO-8859-1 and ASCII are
+// decoded as windows-1252, which is what servers using them almost always
+// send. A byte order mark wins over the label, and an unknown label falls
+// back to UTF-8 or windows-1252 instead of failing the fetch.
+func decodeCharset(label string, data []byte) ([]byte, error) {
+	enc := bomEncoding(data)
+	if enc == nil {
+		var err error
+		if enc, err = htmlindex.Get(strings.TrimSpace(label)); err != nil {
+			if utf8.Valid(data) {
+				return data, nil
+			}
+			enc = charmap.Windows1252
+		}
+	}
+	if enc == unicode.UTF8 {
+		return data, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s text: %w", label, err)
+	}
+	return decoded, nil
+}
+
+// bomEncoding returns the encoding named by a leading byte order mark, or
+// nil when there is none.
+func bomEncoding(data []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(data, []byte("\xff\xfe")):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(data, []byte("\xfe\xff")):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	}
+	return nil
+}
+
+// charsetReader lets encoding/xml read documents that declare a non-UTF-8
+// encoding in their prolog. A document declaring UTF-16 has already been
+// transcoded, since encoding/xml could not have read its prolog otherwise.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	if enc, err := htmlindex.Get(strings.TrimSpace(label)); err == nil {
+		if name, _ := htmlindex.Name(enc); name == "utf-16le" || name == "utf-16be" {
+			return input, nil
+		}
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeCharset(label, data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decoded), nil
+}
+
+func newXMLDecoder(data []byte) *xml.Decoder {
+	if enc := bomEncoding(data); enc != nil && enc != unicode.UTF8BOM {
+		if decoded, err := decodeCharset("", data); err == nil {
+			data = decoded
+		}
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charsetReader
+	return decoder
+}
+
+// hasXMLEncodingDeclaration reports whether the document names its own
+// encoding in the xml prolog.
+func hasXMLEncodingDeclaration(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("<?xml")) {
+		return false
+	}
+	prolog, _, _ := bytes.Cut(data, []byte("?>"))
+	return bytes.Contains(prolog, []byte("encoding="))
+}
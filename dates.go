@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateParser reads the publication dates found in feeds. Values are first
+// normalized into a canonical English form: localized month names become
+// English abbreviations, weekdays and filler words are dropped and named
+// zones become numeric offsets. The result is then tried against layouts in
+// order. Callers can add layouts, zones, month or filler words to support
+// more formats.
+type dateParser struct {
+	layouts []string
+	// zones maps upper-case zone abbreviations to numeric offsets.
+	zones map[string]string
+	// months maps lower-case month names to the English abbreviation.
+	months map[string]string
+	// ignored holds lower-case words that carry no date information, like
+	// weekday names.
+	ignored map[string]bool
+}
+
+var defaultDateParser = dateParser{
+	layouts: []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05-0700",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05 Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05 Z07:00",
+		"2 Jan 2006 15:04 -0700",
+		"2 Jan 06 15:04:05 -0700",
+		"2 Jan 06 15:04 -0700",
+		"2 Jan 2006 15:04:05",
+		"2 Jan 2006 15:04",
+		"2 Jan 2006",
+		"Jan 2 2006 15:04:05 -0700",
+		"Jan 2 2006 15:04 -0700",
+		"Jan 2 2006 3:04 PM -0700",
+		"Jan 2 2006 15:04:05",
+		"Jan 2 2006 3:04 PM",
+		"Jan 2 2006",
+		"Jan 2 15:04:05 -0700 2006", // ANSI C and Unix date
+		"Jan 2 15:04:05 2006",
+	},
+	zones: map[string]string{
+		"UT":   "+0000",
+		"UTC":  "+0000",
+		"GMT":  "+0000",
+		"Z":    "+0000",
+		"EST":  "-0500",
+		"EDT":  "-0400",
+		"CST":  "-0600",
+		"CDT":  "-0500",
+		"MST":  "-0700",
+		"MDT":  "-0600",
+		"PST":  "-0800",
+		"PDT":  "-0700",
+		"AKST": "-0900",
+		"AKDT": "-0800",
+		"HST":  "-1000",
+		"BRT":  "-0300",
+		"BRST": "-0200",
+		"ART":  "-0300",
+		"WET":  "+0000",
+		"WEST": "+0100",
+		"BST":  "+0100",
+		"CET":  "+0100",
+		"CEST": "+0200",
+		"EET":  "+0200",
+		"EEST": "+0300",
+		"MSK":  "+0300",
+		"IST":  "+0530",
+		"JST":  "+0900",
+		"KST":  "+0900",
+		"AEST": "+1000",
+		"AEDT": "+1100",
+		"NZST": "+1200",
+		"NZDT": "+1300",
+	},
+	months: map[string]string{
+		"january": "Jan", "february": "Feb", "march": "Mar", "april": "Apr",
+		"may": "May", "june": "Jun", "july": "Jul", "august": "Aug",
+		"september": "Sep", "sept": "Sep", "october": "Oct", "november": "Nov",
+		"december": "Dec",
+
+		"janeiro": "Jan", "fevereiro": "Feb", "fev": "Feb", "março": "Mar",
+		"marco": "Mar", "abril": "Apr", "abr": "Apr", "maio": "May", "mai": "May",
+		"junho": "Jun", "julho": "Jul", "agosto": "Aug", "ago": "Aug",
+		"setembro": "Sep", "set": "Sep", "outubro": "Oct", "out": "Oct",
+		"novembro": "Nov", "dezembro": "Dec", "dez": "Dec",
+	},
+	ignored: map[string]bool{
+		"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+		"monday": true, "tuesday": true, "wednesday": true, "thursday": true,
+		"friday": true, "saturday": true, "sunday": true, "at": true,
+
+		"seg": true, "ter": true, "qua": true, "qui": true, "sex": true, "sáb": true, "sab": true, "dom": true,
+		"segunda": true, "terça": true, "terca": true, "quarta": true, "quinta": true, "sexta": true,
+		"sábado": true, "sabado": true, "domingo": true, "feira": true,
+		"segunda-feira": true, "terça-feira": true, "terca-feira": true, "quarta-feira": true,
+		"quinta-feira": true, "sexta-feira": true, "de": true, "às": true, "as": true,
+	},
+}
+
+// parseFeedDate parses a date from an RSS, Atom or JSON feed.
+func parseFeedDate(value string) (time.Time, error) {
+	return defaultDateParser.parse(value)
+}
+
+func (p dateParser) parse(value string) (time.Time, error) {
+	normalized := p.normalize(value)
+	for _, layout := range p.layouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("couldn't parse date: %s", value)
+}
+
+// normalize rewrites value into the vocabulary of the layouts, e.g.
+// "Ter, 5 de março de 2024 10:00:00 BRT" becomes "5 Mar 2024 10:00:00 -0300".
+func (p dateParser) normalize(value string) string {
+	value = strings.NewReplacer(",", " ", "\u00a0", " ").Replace(value)
+
+	fields := []string{}
+	for _, field := range strings.Fields(value) {
+		// comments such as the "(UTC)" in "+0000 (UTC)"
+		if strings.HasPrefix(field, "(") && strings.HasSuffix(field, ")") {
+			continue
+		}
+
+		word := strings.ToLower(strings.TrimSuffix(field, "."))
+		if p.ignored[word] {
+			continue
+		}
+		if month, ok := p.months[word]; ok {
+			fields = append(fields, month)
+			continue
+		}
+
+		upper := strings.ToUpper(field)
+		if offset, ok := p.zones[upper]; ok {
+			fields = append(fields, offset)
+			continue
+		}
+		// offsets written relative to a zone, like "GMT+2" or "UTC-03:00"
+		if offset, ok := relativeZoneOffset(upper); ok {
+			fields = append(fields, offset)
+			continue
+		}
+
+		switch upper {
+		case "AM", "PM":
+			field = upper
+		}
+		fields = append(fields, field)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// relativeZoneOffset turns "GMT+2", "UTC-3" and "GMT+05:30" into numeric
+// offsets.
+func relativeZoneOffset(zone string) (string, bool) {
+	rest, ok := strings.CutPrefix(zone, "GMT")
+	if !ok {
+		rest, ok = strings.CutPrefix(zone, "UTC")
+	}
+	if !ok || len(rest) < 2 || (rest[0] != '+' && rest[0] != '-') {
+		return "", false
+	}
+
+	var hours, minutes string
+	digits := rest[1:]
+	switch {
+	case strings.Contains(digits, ":"):
+		hours, minutes, _ = strings.Cut(digits, ":")
+	case len(digits) <= 2:
+		hours, minutes = digits, "00"
+	default:
+		hours, minutes = digits[:len(digits)-2], digits[len(digits)-2:]
+	}
+	if len(hours) == 1 {
+		hours = "0" + hours
+	}
+	if len(hours) != 2 || len(minutes) != 2 || !isDigits(hours+minutes) {
+		return "", false
+	}
+	return string(rest[0]) + hours + minutes, true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFeedDate(t *testing.T) {
+	brt := time.FixedZone("", -3*60*60)
+	est := time.FixedZone("", -5*60*60)
+
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+	}{
+		{"RFC 1123 numeric zone", "Tue, 05 Mar 2024 10:00:00 +0000", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"RFC 1123 GMT", "Tue, 05 Mar 2024 10:00:00 GMT", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"single digit day", "Tue, 5 Mar 2024 10:00:00 +0000", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"no weekday", "5 Mar 2024 10:00:00 +0000", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"two digit year", "Tue, 05 Mar 24 10:00:00 +0000", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"no seconds", "Tue, 05 Mar 2024 10:00 +0000", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"EST", "Tue, 05 Mar 2024 10:00:00 EST", time.Date(2024, 3, 5, 10, 0, 0, 0, est)},
+		{"BRT", "Tue, 05 Mar 2024 10:00:00 BRT", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"lower case zone", "Tue, 05 Mar 2024 10:00:00 brt", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"GMT relative offset", "Tue, 05 Mar 2024 10:00:00 GMT-3", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"zone comment", "Tue, 05 Mar 2024 10:00:00 +0000 (UTC)", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"fractional seconds", "Tue, 05 Mar 2024 10:00:00.250 +0000", time.Date(2024, 3, 5, 10, 0, 0, 250e6, time.UTC)},
+		{"full month name", "March 5, 2024 10:00:00 EST", time.Date(2024, 3, 5, 10, 0, 0, 0, est)},
+		{"US date only", "March 5, 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"12 hour clock", "Mar 5, 2024 3:04 pm", time.Date(2024, 3, 5, 15, 4, 0, 0, time.UTC)},
+		{"Unix date", "Tue Mar  5 10:00:00 EST 2024", time.Date(2024, 3, 5, 10, 0, 0, 0, est)},
+
+		{"RFC 3339", "2024-03-05T10:00:00Z", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"RFC 3339 offset", "2024-03-05T10:00:00-03:00", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"RFC 3339 fraction", "2024-03-05T10:00:00.123456Z", time.Date(2024, 3, 5, 10, 0, 0, 123456000, time.UTC)},
+		{"RFC 3339 fraction offset", "2024-03-05T10:00:00.5-03:00", time.Date(2024, 3, 5, 10, 0, 0, 500e6, brt)},
+		{"RFC 3339 without seconds", "2024-03-05T10:00-03:00", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"RFC 3339 compact offset", "2024-03-05T10:00:00-0300", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"no zone", "2024-03-05T10:00:00", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"SQL style", "2024-03-05 10:00:00", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{"date only", "2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"surrounding whitespace", "  2024-03-05T10:00:00Z\n", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+
+		{"Portuguese", "Ter, 05 Mar 2024 10:00:00 -0300", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"Portuguese month", "Sex, 9 Fev 2024 08:30:00 BRT", time.Date(2024, 2, 9, 8, 30, 0, 0, brt)},
+		{"Portuguese long form", "terça-feira, 5 de março de 2024 10:00:00 BRT", time.Date(2024, 3, 5, 10, 0, 0, 0, brt)},
+		{"Portuguese with às", "5 de Dezembro de 2024 às 18:45", time.Date(2024, 12, 5, 18, 45, 0, 0, time.UTC)},
+		{"Portuguese abbreviation with dot", "Sáb, 10 set. 2024 09:00:00 -0300", time.Date(2024, 9, 10, 9, 0, 0, 0, brt)},
+		{"Portuguese date only", "1 de janeiro de 2025", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeedDate(tt.input)
+			if err != nil {
+				t.Fatalf("parseFeedDate(%q) returned error: %v", tt.input, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseFeedDate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedDateInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"yesterday",
+		"not a date at all",
+		"2024-13-45",
+		"Tue, 05 Foo 2024 10:00:00 +0000",
+		"Tue, 05 Mar 2024 10:00:00 XYZ",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if got, err := parseFeedDate(input); err == nil {
+				t.Errorf("parseFeedDate(%q) = %v, want error", input, got)
+			}
+		})
+	}
+}
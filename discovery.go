@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+}
+
+// commonFeedPaths are tried, in order, when a page doesn't advertise feeds.
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss.xml",
+	"/feed.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/rss",
+	"/feed.json",
+}
+
+// resolveFeedURL returns the feed behind rawURL. A feed URL is returned as
+// is, together with the parsed feed; an HTML page is searched for the feeds
+// it links to. When the page offers several feeds they are all returned so
+// the user can pick one.
+func resolveFeedURL(ctx context.Context, s *state, rawURL string) ([]string, *RSSFeed, error) {
+	result, err := fetchFeed(ctx, s, rawURL, feedValidators{})
+	if err == nil {
+		return []string{rawURL}, result.Feed, nil
+	}
+
+	var typeErr *contentTypeError
+	if !errors.As(err, &typeErr) || !typeErr.isHTML() {
+		return nil, nil, fmt.Errorf("error fetching feed: %w", err)
+	}
+
+	candidates, err := discoverFeeds(ctx, s, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("%s is a web page and no feed was found on it", rawURL)
+	}
+	return candidates, nil, nil
+}
+
+// discoverFeeds looks for <link rel="alternate"> feed candidates on an HTML
+// page, falling back to probing common feed paths on the same host.
+func discoverFeeds(ctx context.Context, s *state, pageURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error making new request with context: %w", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := readLimitedBody(resp, s.maxFeedBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// links are relative to where the page ended up after redirects
+	base := resp.Request.URL
+	candidates := []string{}
+	for _, token := range tokenizeHTML(string(b)) {
+		if token.Type != htmlStartTag && token.Type != htmlSelfClosingTag {
+			continue
+		}
+
+		switch token.Data {
+		case "base":
+			if href, ok := token.attr("href"); ok {
+				if resolved, err := base.Parse(strings.TrimSpace(href)); err == nil {
+					base = resolved
+				}
+			}
+		case "link":
+			rel, _ := token.attr("rel")
+			linkType, _ := token.attr("type")
+			href, _ := token.attr("href")
+			if href == "" || !slices.Contains(strings.Fields(strings.ToLower(rel)), "alternate") {
+				continue
+			}
+			if !slices.Contains(feedLinkTypes, mediaType(linkType)) {
+				continue
+			}
+			resolved, err := base.Parse(strings.TrimSpace(href))
+			if err != nil {
+				continue
+			}
+			if candidate := resolved.String(); !slices.Contains(candidates, candidate) {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		candidate := (&url.URL{Scheme: base.Scheme, Host: base.Host, Path: path}).String()
+		if _, err := fetchFeed(ctx, s, candidate, feedValidators{}); err == nil {
+			return []string{candidate}, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IlMeloIl/RSS/internal/database"
+	"github.com/google/uuid"
+)
+
+// saveEnclosures replaces the stored enclosures of a post with the ones the
+// feed currently lists for it.
+func saveEnclosures(ctx context.Context, s *state, postID uuid.UUID, enclosures []RSSEnclosure) error {
+	if err := s.db.DeleteEnclosuresForPost(ctx, postID); err != nil {
+		return fmt.Errorf("error deleting old enclosures: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, enclosure := range enclosures {
+		if enclosure.URL == "" || seen[enclosure.URL] {
+			continue
+		}
+		seen[enclosure.URL] = true
+
+		params := database.CreateEnclosureParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			PostID:    postID,
+			Url:       enclosure.URL,
+			MimeType:  sql.NullString{String: enclosure.Type, Valid: enclosure.Type != ""},
+			ImageUrl:  sql.NullString{String: enclosure.Image, Valid: enclosure.Image != ""},
+		}
+		if length, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && length > 0 {
+			params.Length = sql.NullInt64{Int64: length, Valid: true}
+		}
+		if seconds, ok := parseMediaDuration(enclosure.Duration); ok {
+			params.DurationSeconds = sql.NullInt32{Int32: seconds, Valid: true}
+		}
+
+		if err := s.db.CreateEnclosure(ctx, params); err != nil {
+			return fmt.Errorf("error saving enclosure %s: %w", enclosure.URL, err)
+		}
+	}
+	return nil
+}
+
+// parseMediaDuration reads itunes:duration and media:content durations,
+// which are either plain seconds or [[HH:]MM:]SS, optionally fractional.
+func parseMediaDuration(value string) (int32, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	return int32(total), true
+}
+
+// formatEnclosure describes an enclosure in one line, e.g.
+// "https://example.com/ep1.mp3 (audio/mpeg, 24.1 MB, 1h02m03s)".
+func formatEnclosure(url string, mimeType sql.NullString, length sql.NullInt64, duration sql.NullInt32) string {
+	details := []string{}
+	if mimeType.Valid {
+		details = append(details, mimeType.String)
+	}
+	if length.Valid {
+		details = append(details, formatBytes(length.Int64))
+	}
+	if duration.Valid {
+		details = append(details, (time.Duration(duration.Int32) * time.Second).String())
+	}
+	if len(details) == 0 {
+		return url
+	}
+	return fmt.Sprintf("%s (%s)", url, strings.Join(details, ", "))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
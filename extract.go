@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// htmlNode is an element or text node of a parsed HTML page. Text nodes
+// have an empty Tag.
+type htmlNode struct {
+	Tag      string
+	Attrs    []htmlAttr
+	Text     string
+	Parent   *htmlNode
+	Children []*htmlNode
+}
+
+func (n *htmlNode) attr(key string) string {
+	for _, a := range n.Attrs {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func (n *htmlNode) appendChild(child *htmlNode) {
+	child.Parent = n
+	n.Children = append(n.Children, child)
+}
+
+// closesParagraph lists the start tags that implicitly end an open <p>.
+var closesParagraph = map[string]bool{
+	"p": true, "div": true, "ul": true, "ol": true, "dl": true, "table": true,
+	"blockquote": true, "pre": true, "section": true, "article": true,
+	"aside": true, "header": true, "footer": true, "nav": true, "figure": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "form": true,
+}
+
+// parseHTMLTree builds a node tree from a page. It is not a full HTML5 tree
+// builder, but handles what pages commonly rely on: void elements, implied
+// </p> and </li>, and stray end tags.
+func parseHTMLTree(s string) *htmlNode {
+	root := &htmlNode{Tag: "#document"}
+	current := root
+
+	for _, token := range tokenizeHTML(s) {
+		switch token.Type {
+		case htmlText:
+			current.appendChild(&htmlNode{Text: token.Data})
+
+		case htmlStartTag, htmlSelfClosingTag:
+			if (closesParagraph[token.Data] && current.Tag == "p") || (token.Data == "li" && current.Tag == "li") {
+				current = current.Parent
+			}
+			node := &htmlNode{Tag: token.Data, Attrs: token.Attrs}
+			current.appendChild(node)
+			if token.Type == htmlStartTag && !voidElements[token.Data] {
+				current = node
+			}
+
+		case htmlEndTag:
+			for n := current; n != root; n = n.Parent {
+				if n.Tag == token.Data {
+					current = n.Parent
+					break
+				}
+			}
+		}
+	}
+
+	return root
+}
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|ad-break|agegate|nav`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|post|entry`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|nav|menu`)
+)
+
+// boilerplateElements never hold the article body.
+var boilerplateElements = map[string]bool{
+	"nav":    true,
+	"header": true,
+	"footer": true,
+	"aside":  true,
+	"button": true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+}
+
+var boilerplateRoles = map[string]bool{
+	"navigation":    true,
+	"complementary": true,
+	"banner":        true,
+	"contentinfo":   true,
+	"dialog":        true,
+	"menu":          true,
+}
+
+// blockElements make a <div> a container rather than a paragraph of text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "ul": true, "ol": true, "dl": true, "table": true,
+	"blockquote": true, "pre": true, "section": true, "article": true,
+	"figure": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "img": true,
+}
+
+// minArticleLength is the shortest text, in characters, accepted as an
+// extracted article. Anything shorter is most likely a teaser or a menu.
+const minArticleLength = 140
+
+// extractArticle finds the main article of a page with a readability-style
+// scoring of its paragraphs and returns it as sanitized HTML with absolute
+// links.
+func extractArticle(page string, pageURL *url.URL) (string, error) {
+	root := parseHTMLTree(page)
+	base := pageURL
+	if baseNode := findElement(root, "base"); baseNode != nil {
+		if resolved, err := pageURL.Parse(strings.TrimSpace(baseNode.attr("href"))); err == nil {
+			base = resolved
+		}
+	}
+
+	pruneBoilerplate(root)
+
+	scores := map[*htmlNode]float64{}
+	candidates := []*htmlNode{}
+	addScore := func(node *htmlNode, score float64) {
+		if node == nil || node.Tag == "" || node.Tag == "#document" {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	walkElements(root, func(node *htmlNode) {
+		switch node.Tag {
+		case "p", "pre", "td", "blockquote":
+		case "div":
+			if hasBlockChild(node) {
+				return
+			}
+		default:
+			return
+		}
+
+		text := innerText(node)
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(length/100), 3)
+		addScore(node.Parent, score)
+		if node.Parent != nil {
+			addScore(node.Parent.Parent, score/2)
+		}
+	})
+
+	var top *htmlNode
+	for _, candidate := range candidates {
+		scores[candidate] *= 1 - linkDensity(candidate)
+		if top == nil || scores[candidate] > scores[top] {
+			top = candidate
+		}
+	}
+	if top == nil {
+		return "", fmt.Errorf("no article found on page")
+	}
+
+	// content is often split across siblings of the best candidate, like
+	// consecutive paragraphs without a common wrapper
+	threshold := max(10, scores[top]*0.2)
+	article := strings.Builder{}
+	for _, sibling := range top.Parent.Children {
+		if sibling != top && !relatedSibling(sibling, top, scores, threshold) {
+			continue
+		}
+		renderNode(&article, sibling)
+	}
+
+	content := resolveHTMLURLs(sanitizeHTML(article.String()), base)
+	if utf8.RuneCountInString(htmlToSummaryText(content)) < minArticleLength {
+		return "", fmt.Errorf("no article found on page")
+	}
+	return content, nil
+}
+
+func relatedSibling(sibling, top *htmlNode, scores map[*htmlNode]float64, threshold float64) bool {
+	if sibling.Tag == "" {
+		return false
+	}
+	bonus := 0.0
+	if class := top.attr("class"); class != "" && sibling.attr("class") == class {
+		bonus = scores[top] * 0.2
+	}
+	if score, ok := scores[sibling]; ok && score+bonus >= threshold {
+		return true
+	}
+	if sibling.Tag != "p" {
+		return false
+	}
+
+	text := innerText(sibling)
+	length := utf8.RuneCountInString(text)
+	density := linkDensity(sibling)
+	switch {
+	case length > 80:
+		return density < 0.25
+	case length > 0:
+		return density == 0 && strings.HasSuffix(text, ".")
+	}
+	return false
+}
+
+// pruneBoilerplate removes scripts, navigation, sidebars and other elements
+// that are unlikely to be part of the article.
+func pruneBoilerplate(node *htmlNode) {
+	kept := node.Children[:0]
+	for _, child := range node.Children {
+		if child.Tag != "" && isBoilerplate(child) {
+			continue
+		}
+		pruneBoilerplate(child)
+		kept = append(kept, child)
+	}
+	node.Children = kept
+}
+
+func isBoilerplate(node *htmlNode) bool {
+	if droppedElements[node.Tag] || boilerplateElements[node.Tag] {
+		return true
+	}
+	if boilerplateRoles[strings.ToLower(node.attr("role"))] {
+		return true
+	}
+	switch node.Tag {
+	case "html", "body", "article", "main", "a":
+		return false
+	}
+	match := node.attr("class") + " " + node.attr("id")
+	return unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match)
+}
+
+func initialScore(node *htmlNode) float64 {
+	score := 0.0
+	switch node.Tag {
+	case "div", "article", "main":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	for _, value := range []string{node.attr("class"), node.attr("id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			score -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+func hasBlockChild(node *htmlNode) bool {
+	for _, child := range node.Children {
+		if blockElements[child.Tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// innerText is the whitespace-collapsed text of a node and its descendants.
+func innerText(node *htmlNode) string {
+	text := strings.Builder{}
+	var collect func(*htmlNode)
+	collect = func(n *htmlNode) {
+		if n.Tag == "" {
+			text.WriteString(n.Text)
+			text.WriteByte(' ')
+		}
+		for _, child := range n.Children {
+			collect(child)
+		}
+	}
+	collect(node)
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// linkDensity is the share of a node's text that sits inside links.
+func linkDensity(node *htmlNode) float64 {
+	length := utf8.RuneCountInString(innerText(node))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	walkElements(node, func(n *htmlNode) {
+		if n.Tag == "a" {
+			linkLength += utf8.RuneCountInString(innerText(n))
+		}
+	})
+	return min(float64(linkLength)/float64(length), 1)
+}
+
+// walkElements calls fn for node and every element below it, skipping the
+// children of links so nested anchors aren't counted twice.
+func walkElements(node *htmlNode, fn func(*htmlNode)) {
+	if node.Tag != "" {
+		fn(node)
+	}
+	if node.Tag == "a" {
+		return
+	}
+	for _, child := range node.Children {
+		walkElements(child, fn)
+	}
+}
+
+func findElement(node *htmlNode, tag string) *htmlNode {
+	if node.Tag == tag {
+		return node
+	}
+	for _, child := range node.Children {
+		if found := findElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// renderNode serializes a node back to HTML.
+func renderNode(out *strings.Builder, node *htmlNode) {
+	if node.Tag == "" {
+		out.WriteString(html.EscapeString(node.Text))
+		return
+	}
+
+	out.WriteString("<" + node.Tag)
+	for _, attr := range node.Attrs {
+		out.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	out.WriteString(">")
+	if voidElements[node.Tag] {
+		return
+	}
+	for _, child := range node.Children {
+		renderNode(out, child)
+	}
+	out.WriteString("</" + node.Tag + ">")
+}
+
+// fetchArticle downloads a post's page and extracts its article body.
+func fetchArticle(ctx context.Context, s *state, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error making new request with context: %w", err)
+	}
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching article: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mt := mediaType(contentType); mt != "" && mt != "text/html" && mt != "application/xhtml+xml" {
+		return "", fmt.Errorf("article is not a web page: content type %s", contentType)
+	}
+
+	b, err := readLimitedBody(resp, s.maxFeedBytes)
+	if err != nil {
+		return "", err
+	}
+
+	label := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	if label == "" {
+		label = metaCharset(b)
+	}
+	if label != "" {
+		if b, err = decodeCharset(label, b); err != nil {
+			return "", err
+		}
+	}
+
+	return extractArticle(string(b), resp.Request.URL)
+}
+
+// metaCharset finds the charset declared by a page's <meta> tags within its
+// first kilobytes, as browsers do.
+func metaCharset(page []byte) string {
+	head := string(page[:min(len(page), 4096)])
+	for _, token := range tokenizeHTML(head) {
+		if (token.Type != htmlStartTag && token.Type != htmlSelfClosingTag) || token.Data != "meta" {
+			continue
+		}
+		if charset, ok := token.attr("charset"); ok {
+			return strings.TrimSpace(charset)
+		}
+		if equiv, _ := token.attr("http-equiv"); strings.EqualFold(equiv, "content-type") {
+			content, _ := token.attr("content")
+			if _, params, err := mime.ParseMediaType(content); err == nil {
+				return params["charset"]
+			}
+		}
+	}
+	return ""
+}
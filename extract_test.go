@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IlMeloIl/RSS/internal/config"
+)
+
+const blogPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Why tests matter - Example Blog</title>
+  <script>trackPageView();</script>
+  <style>body { font-family: serif; }</style>
+</head>
+<body>
+  <header class="site-header">
+    <nav><a href="/">Home</a> <a href="/about">About</a> <a href="/archive">Archive</a></nav>
+  </header>
+  <div id="main-wrapper">
+    <div class="sidebar">
+      <h3>Popular posts</h3>
+      <ul>
+        <li><a href="/p/1">Sidebar link one, with a long title that goes on</a></li>
+        <li><a href="/p/2">Sidebar link two, with another long title</a></li>
+      </ul>
+    </div>
+    <article class="post">
+      <h1>Why tests matter</h1>
+      <div class="post-body">
+        <p>Tests catch regressions before they reach users, which saves time, money and a good deal of embarrassment for everyone involved.</p>
+        <p>They also document intent. A reader can see how a function is meant to be called, what it returns and which edge cases were considered.</p>
+        <p>Finally, tests make refactoring safe. You can restructure code with confidence, knowing that <a href="/guides/testing">the suite</a> will complain if behaviour changes.</p>
+        <script>alert("inline");</script>
+      </div>
+    </article>
+    <div id="comments">
+      <h3>Comments</h3>
+      <p>Great post, thanks for writing it, I agree with all of it!</p>
+    </div>
+  </div>
+  <footer>Copyright Example Blog, all rights reserved, forever and ever.</footer>
+</body>
+</html>`
+
+const unwrappedPage = `<html><body>
+<div class="menu"><a href="/a">A</a> | <a href="/b">B</a></div>
+<h1>Plain page</h1>
+<p>This page has no article wrapper at all, just a list of paragraphs that sit directly in the body of the document.</p>
+<p>The extractor should still find them, because paragraphs score their parent, and the body is the parent here.</p>
+<p>And it should keep all of them, in order, without the menu that sits at the top of the page.</p>
+</body></html>`
+
+const teaserPage = `<html><body>
+<nav><a href="/">Home</a></nav>
+<p>Subscribe to read.</p>
+</body></html>`
+
+func TestFetchArticle(t *testing.T) {
+	latin1 := toLatin1(t, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=iso-8859-1"></head><body><div class="content">`+
+		`<p>Acentuação é importante, e este parágrafo está codificado em ISO-8859-1, não em UTF-8, como muitos sites antigos.</p>`+
+		`<p>O extrator precisa decodificar a página antes de analisá-la, senão os acentos viram lixo na tela do leitor.</p>`+
+		`</div></body></html>`)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/why-tests-matter", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(blogPage))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(unwrappedPage))
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(latin1)
+	})
+	mux.HandleFunc("/teaser", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(teaserPage))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := newHTTPClient(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &state{client: client, maxFeedBytes: maxFeedBytes(&config.Config{})}
+
+	tests := []struct {
+		name     string
+		path     string
+		contains []string
+		excludes []string
+		wantErr  bool
+	}{
+		{
+			name: "article between navigation, sidebar and comments",
+			path: "/blog/why-tests-matter",
+			contains: []string{
+				"Tests catch regressions",
+				"They also document intent.",
+				"Finally, tests make refactoring safe.",
+				`href="` + server.URL + `/guides/testing"`,
+			},
+			excludes: []string{"Archive", "Sidebar link", "Great post", "Copyright", "alert", "trackPageView", "font-family"},
+		},
+		{
+			name: "paragraphs directly in the body",
+			path: "/plain",
+			contains: []string{
+				"This page has no article wrapper",
+				"The extractor should still find them",
+				"And it should keep all of them",
+			},
+			excludes: []string{`href="` + server.URL + `/a"`},
+		},
+		{
+			name:     "charset from meta tag",
+			path:     "/latin1",
+			contains: []string{"Acentuação é importante", "analisá-la"},
+		},
+		{name: "teaser only", path: "/teaser", wantErr: true},
+		{name: "not a web page", path: "/image.png", wantErr: true},
+		{name: "missing page", path: "/missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article, err := fetchArticle(context.Background(), s, server.URL+tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("fetchArticle(%s) = %q, want error", tt.path, article)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchArticle(%s) returned error: %v", tt.path, err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(article, want) {
+					t.Errorf("article is missing %q:\n%s", want, article)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(article, unwanted) {
+					t.Errorf("article contains %q:\n%s", unwanted, article)
+				}
+			}
+		})
+	}
+}
+
+func toLatin1(t *testing.T, s string) []byte {
+	t.Helper()
+	out := []byte{}
+	for _, r := range s {
+		if r > 0xff {
+			t.Fatalf("%q can't be encoded as latin-1", r)
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
@@ -4,4 +4,8 @@ go 1.24.0
 
 require github.com/google/uuid v1.6.0
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/lib/pq v1.10.9
+	golang.org/x/text v0.34.0
+)
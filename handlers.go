@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/xml"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"html"
-	"io"
 	"log"
+	"math/rand/v2"
+	"mime"
 	"net/http"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -105,29 +107,77 @@ func HandlerUsers(s *state, cmd command) error {
 	return nil
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+func fetchFeed(ctx context.Context, s *state, feedURL string, validators feedValidators) (*fetchResult, error) {
 
+	ctx, trace := withRedirectTrace(ctx)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error making new request with context: %w", err)
 	}
 	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error returning resp: %w", err)
 	}
 	defer resp.Body.Close()
 
-	b, err := io.ReadAll(resp.Body)
+	result := &fetchResult{
+		Validators: feedValidators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+		StatusCode:   resp.StatusCode,
+		PermanentURL: trace.permanentURL,
+		FinalURL:     resp.Request.URL.String(),
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		result.Validators = validators
+		return result, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if isNonFeedContentType(contentType) {
+		return nil, &contentTypeError{ContentType: contentType}
+	}
+
+	b, err := readLimitedBody(resp, s.maxFeedBytes)
 	if err != nil {
-		return nil, fmt.Errorf("erro reading all from response body: %w", err)
+		return nil, err
 	}
 
-	v := RSSFeed{}
-	if err := xml.Unmarshal(b, &v); err != nil {
-		return nil, fmt.Errorf("error unmarshaling xml: %w", err)
+	// the Content-Type charset only applies when the document doesn't
+	// declare its own encoding
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" && !hasXMLEncodingDeclaration(b) {
+		if b, err = decodeCharset(params["charset"], b); err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := parseFeed(b, contentType)
+	if err != nil {
+		if mediaType(contentType) == "text/html" {
+			return nil, &contentTypeError{ContentType: contentType}
+		}
+		// pages served without a useful content type are still web pages
+		if sniffed := http.DetectContentType(b); mediaType(sniffed) == "text/html" {
+			return nil, &contentTypeError{ContentType: sniffed}
+		}
+		return nil, err
 	}
 
 	v.Channel.Title = html.UnescapeString(v.Channel.Title)
@@ -138,153 +188,477 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		v.Channel.Item[i].Description = html.UnescapeString(v.Channel.Item[i].Description)
 	}
 
-	return &v, nil
+	result.Feed = v
+	return result, nil
 
 }
 
-func parseRSSDate(dateStr string) (time.Time, error) {
-	formats := []string{
-		time.RFC1123Z,
-		time.RFC1123,
-		time.RFC822,
-		time.RFC822Z,
-		"Mon, 02 Jan 2006 15:04:05 Z",
-		"2006-01-02T15:04:05Z",
-		"2006-01-02T15:04:05-07:00",
-		"2006-01-02 15:04:05",
+// itemGUID identifies an item within its feed. Items without a guid fall
+// back to their link, and then to their title.
+func itemGUID(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
 	}
+	return item.Title
+}
 
-	for _, format := range formats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			return t, nil
-		}
+// itemContentHash fingerprints the parts of an item shown to users, so edits
+// upstream can be told apart from a plain re-fetch.
+func itemContentHash(item RSSItem) string {
+	h := sha256.New()
+	for _, field := range []string{item.Title, item.Description, item.PubDate} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	// content is only hashed when present so that posts stored before it was
+	// captured don't all turn into new revisions
+	if item.Content != "" {
+		h.Write([]byte(item.Content))
+		h.Write([]byte{0})
 	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	return time.Time{}, fmt.Errorf("couldn't parse date: %s", dateStr)
+// itemMetadataHash fingerprints what is stored alongside a post, such as its
+// enclosures, authors and categories, so changes to it are saved without
+// counting as a new revision. Items with none of it hash to "", like posts
+// stored before it was tracked.
+func itemMetadataHash(item RSSItem) string {
+	fields := []string{}
+	for _, enclosure := range item.Enclosures {
+		fields = append(fields, enclosure.URL, enclosure.Type, enclosure.Length, enclosure.Duration, enclosure.Image)
+	}
+	for _, author := range itemAuthors(item) {
+		fields = append(fields, "author", author)
+	}
+	for _, category := range itemCategories(item) {
+		fields = append(fields, "category", category)
+	}
+	if item.Comments != "" {
+		fields = append(fields, "comments", item.Comments)
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, field := range fields {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-func stripHTMLTags(s string) string {
-	re := regexp.MustCompile("<[^>]*>")
-	s = re.ReplaceAllString(s, "")
+// deadFeedFailures is how many fetches in a row may fail before a feed is
+// considered dead and no longer scheduled.
+const deadFeedFailures = 10
 
-	s = strings.TrimSpace(s)
+const (
+	failureBackoffBase = time.Minute
+	failureBackoffMax  = 24 * time.Hour
+)
 
-	return s
+// failureBackoff doubles the wait after each consecutive failure up to
+// failureBackoffMax, with 20% jitter so failing feeds don't retry in lockstep.
+func failureBackoff(failures int32) time.Duration {
+	shift := min(max(failures-1, 0), 20)
+	backoff := min(failureBackoffBase<<shift, failureBackoffMax)
+	jitter := time.Duration(rand.Int64N(int64(backoff)*2/5)) - backoff/5
+	return backoff + jitter
 }
 
-func scrapeFeeds(s *state) error {
-
-	ctx := context.Background()
+// recordFeedFailure counts a failed fetch, backs the feed off, and marks the
+// feed dead when it is gone for good or has failed too many times in a row.
+func recordFeedFailure(ctx context.Context, s *state, feed database.Feed, fetchErr error) error {
+	statusCode := sql.NullInt32{}
+	var statusErr *httpStatusError
+	if errors.As(fetchErr, &statusErr) {
+		statusCode = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+	}
 
-	feeds, err := s.db.GetFeeds(ctx)
+	nextFetchAt := time.Now().UTC().Add(failureBackoff(feed.ConsecutiveFailures + 1))
+	failures, err := s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		ID:             feed.ID,
+		LastStatusCode: statusCode,
+		LastError:      sql.NullString{String: fetchErr.Error(), Valid: true},
+		NextFetchAt:    sql.NullTime{Time: nextFetchAt, Valid: true},
+	})
 	if err != nil {
-		return fmt.Errorf("error getting feeds: %w", err)
+		return fmt.Errorf("error recording feed failure: %w", err)
 	}
-	fmt.Printf("Total feeds in database: %d\n", len(feeds))
 
-	for _, feedInfo := range feeds {
-		feed, err := s.db.GetFeedByURL(ctx, feedInfo.Url)
-		if err != nil {
-			continue
-		}
-		fmt.Printf("Feed: %s, Last fetched: %v\n", feed.Name, feed.LastFetchedAt)
+	if statusCode.Int32 != http.StatusGone && failures < deadFeedFailures {
+		return nil
 	}
 
-	feed, err := s.db.GetNextFeedToFetch(ctx)
+	if err = s.db.MarkFeedDead(ctx, feed.ID); err != nil {
+		return fmt.Errorf("error marking feed as dead: %w", err)
+	}
+	fmt.Printf("Feed %s marked as dead after %d consecutive failures\n", feed.Name, failures)
+	return nil
+}
+
+// moveFeed rewrites the feed url after a permanent redirect. Follows and
+// posts point at the feed id, so they move along with it.
+func moveFeed(ctx context.Context, s *state, feed database.Feed, newURL string) error {
+	canonical, err := canonicalFeedURL(newURL)
 	if err != nil {
-		return fmt.Errorf("error getting next feed to fetch: %w", err)
+		return err
+	}
+
+	existing, err := getFeedByAnyURL(ctx, s, newURL)
+	if err == nil && existing.ID != feed.ID {
+		return fmt.Errorf("url already belongs to feed %s", existing.Name)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error getting feed by url: %w", err)
 	}
 
+	if err = s.db.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+		ID:           feed.ID,
+		Url:          newURL,
+		CanonicalUrl: sql.NullString{String: canonical, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("error updating feed url: %w", err)
+	}
+	fmt.Printf("Feed %s permanently moved to %s\n", feed.Name, newURL)
+	return nil
+}
+
+// scrapeFeed fetches a single claimed feed and stores its items.
+func scrapeFeed(ctx context.Context, s *state, feed database.Feed, limits pollingLimits) error {
 	fmt.Printf("\nFETCHING FEED: %s (ID: %s)\n", feed.Name, feed.ID)
-	fmt.Printf("Last fetched at: %v\n", feed.LastFetchedAt)
 
-	if err = s.db.MarkFeedFetched(ctx, feed.ID); err != nil {
+	result, err := fetchFeed(ctx, s, feed.Url, feedValidators{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+	})
+
+	// marking the feed fetched also releases the claim, so this waits until
+	// the request is over; a crash before it leaves the lease to expire instead
+	if err := s.db.MarkFeedFetched(ctx, feed.ID); err != nil {
 		return fmt.Errorf("error marking feed as fetched: %w", err)
 	}
 
-	updatedFeed, _ := s.db.GetFeedByURL(ctx, feed.Url)
-	fmt.Printf("Feed marked as fetched. New last_fetched_at: %v\n", updatedFeed.LastFetchedAt)
-
-	rssFeed, err := fetchFeed(ctx, feed.Url)
 	if err != nil {
+		if recordErr := recordFeedFailure(ctx, s, feed, err); recordErr != nil {
+			fmt.Printf("error recording failure of feed %s: %v\n", feed.Name, recordErr)
+		}
 		return fmt.Errorf("error fetching feed in scrape feeds: %w", err)
 	}
 
+	// a 304 has no body to read hints from, so the stored ones still apply
+	hints := refreshHintsFromDB(feed)
+	if result.Feed != nil {
+		hints = refreshHintsFromFeed(result.Feed)
+		if err = s.db.UpdateFeedRefreshHints(ctx, database.UpdateFeedRefreshHintsParams{
+			ID:                 feed.ID,
+			MinIntervalSeconds: hints.minIntervalSeconds(),
+			SkipHours:          hints.SkipHours,
+			SkipDays:           hints.SkipDays,
+		}); err != nil {
+			return fmt.Errorf("error saving feed refresh hints: %w", err)
+		}
+	}
+
+	if err = s.db.RecordFeedSuccess(ctx, database.RecordFeedSuccessParams{
+		ID:             feed.ID,
+		LastStatusCode: sql.NullInt32{Int32: int32(result.StatusCode), Valid: true},
+		NextFetchAt:    hints.nextFetch(time.Now()),
+	}); err != nil {
+		return fmt.Errorf("error recording feed status: %w", err)
+	}
+
+	if result.PermanentURL != "" && result.PermanentURL != feed.Url {
+		if err = moveFeed(ctx, s, feed, result.PermanentURL); err != nil {
+			fmt.Printf("error moving feed %s to %s: %v\n", feed.Name, result.PermanentURL, err)
+		}
+	}
+
+	if result.NotModified {
+		fmt.Println("Feed not modified since last fetch")
+		return scheduleFeed(ctx, s, feed, hints, limits)
+	}
+
+	if err = s.db.UpdateFeedCacheHeaders(ctx, database.UpdateFeedCacheHeadersParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: result.Validators.ETag, Valid: result.Validators.ETag != ""},
+		LastModified: sql.NullString{String: result.Validators.LastModified, Valid: result.Validators.LastModified != ""},
+	}); err != nil {
+		return fmt.Errorf("error saving feed cache headers: %w", err)
+	}
+
+	rssFeed := result.Feed
+	resolveItemURLs(rssFeed, result.FinalURL)
+
+	// posts stored before guids were tracked still have their url as guid
+	// and are matched by url; feeds without any skip that work
+	hasBackfilledGUIDs, err := s.db.FeedHasBackfilledPostGUIDs(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("error checking for posts without guids: %w", err)
+	}
+
 	for _, item := range rssFeed.Channel.Item {
 
-		var publishedAt sql.NullTime
+		// items without a usable date are dated when first seen and flagged,
+		// so the upsert keeps the first date and cadence learning skips them
+		firstSeen := time.Now().UTC()
+		publishedAt := sql.NullTime{Time: firstSeen, Valid: true}
+		dateUnknown := true
 		if item.PubDate != "" {
-			parsedTime, err := parseRSSDate(item.PubDate)
+			parsedTime, err := parseFeedDate(item.PubDate)
 			if err != nil {
 				fmt.Printf("Warning: couldn't parse date '%s': %v\n", item.PubDate, err)
 			} else {
-				publishedAt = sql.NullTime{
-					Time:  parsedTime,
-					Valid: true,
-				}
+				// published_at has no time zone, so the offset would be dropped
+				publishedAt.Time = parsedTime.UTC()
+				dateUnknown = false
 			}
 		}
 
 		description := item.Description
-		cleanDescription := stripHTMLTags(description)
+		cleanDescription := htmlToSummaryText(description)
 
 		if cleanDescription == "" || cleanDescription == "Comments" {
 			cleanDescription = "[No description available]"
 		}
 
-		_, err := s.db.CreatePost(ctx, database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       sql.NullString{String: item.Title, Valid: true},
-			Url:         item.Link,
-			Description: sql.NullString{String: cleanDescription, Valid: cleanDescription != ""},
-			PublishedAt: publishedAt,
-			FeedID:      feed.ID,
+		content := sanitizeHTML(item.Content)
+		contentText := htmlToText(content)
+
+		guid := itemGUID(item)
+		if hasBackfilledGUIDs && guid != item.Link && item.Link != "" {
+			if err := s.db.AdoptBackfilledPostGUID(ctx, database.AdoptBackfilledPostGUIDParams{
+				Guid:   guid,
+				FeedID: feed.ID,
+				Url:    item.Link,
+			}); err != nil {
+				fmt.Printf("Error matching stored post for %s: %v\n", item.Title, err)
+				continue
+			}
+		}
+
+		postID := uuid.New()
+		post, err := s.db.UpsertPost(ctx, database.UpsertPostParams{
+			ID:           postID,
+			CreatedAt:    firstSeen,
+			UpdatedAt:    firstSeen,
+			Title:        sql.NullString{String: item.Title, Valid: true},
+			Url:          item.Link,
+			Description:  sql.NullString{String: cleanDescription, Valid: cleanDescription != ""},
+			PublishedAt:  publishedAt,
+			FeedID:       feed.ID,
+			Guid:         guid,
+			ContentHash:  itemContentHash(item),
+			Content:      sql.NullString{String: content, Valid: content != ""},
+			ContentText:  sql.NullString{String: contentText, Valid: contentText != ""},
+			CommentsUrl:  sql.NullString{String: item.Comments, Valid: item.Comments != ""},
+			MetadataHash: itemMetadataHash(item),
+			DateUnknown:  dateUnknown,
 		})
 		if err != nil {
-			if strings.Contains(err.Error(), "unique constraint") ||
-				strings.Contains(err.Error(), "duplicate key") {
+			// the upsert returns no row when the item is already stored unchanged
+			if err == sql.ErrNoRows {
 				continue
 			}
 			fmt.Printf("Error saving post: %v\n", err)
-		} else {
+			continue
+		} else if post.ID == postID {
 			fmt.Printf("Saved post: %s\n", item.Title)
+		} else {
+			fmt.Printf("Updated post: %s (revision %d)\n", item.Title, post.Revision)
+		}
+
+		if err := saveEnclosures(ctx, s, post.ID, item.Enclosures); err != nil {
+			fmt.Printf("Error saving enclosures for %s: %v\n", item.Title, err)
+		}
+		if err := savePostMetadata(ctx, s, post.ID, item); err != nil {
+			fmt.Printf("Error saving authors and categories for %s: %v\n", item.Title, err)
+		}
+
+		if feed.FetchFullContent && post.ID == postID && post.Url != "" {
+			saveFullContent(ctx, s, post)
 		}
 	}
-	return nil
+	return scheduleFeed(ctx, s, feed, hints, limits)
 }
 
 func HandlerAgg(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("command agg needs one argument: agg <time_between_reqs> --> <5s>, <1m>, <1h>")
+	args, flags, err := cmd.parseFlags()
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("command agg needs one argument: agg <time_between_reqs> [--workers <n>] [--batch <n>] [--lease <duration>] [--min-interval <duration>] [--max-interval <duration>] --> <5s>, <1m>, <1h>")
 	}
 
-	timeBetweenReqsStr := cmd.args[0]
+	timeBetweenReqsStr := args[0]
 	timeBetweenReqs, err := time.ParseDuration(timeBetweenReqsStr)
 	if err != nil {
 		return fmt.Errorf("error parsing time duration: %w", err)
 	}
 
+	workers := 4
+	if value, ok := flags["workers"]; ok {
+		workers, err = strconv.Atoi(value)
+		if err != nil || workers < 1 {
+			return fmt.Errorf("--workers must be a positive number")
+		}
+	}
+
+	batch := workers
+	if value, ok := flags["batch"]; ok {
+		batch, err = strconv.Atoi(value)
+		if err != nil || batch < 1 {
+			return fmt.Errorf("--batch must be a positive number")
+		}
+	}
+
+	lease := 5 * time.Minute
+	if value, ok := flags["lease"]; ok {
+		lease, err = time.ParseDuration(value)
+		if err != nil || lease <= 0 {
+			return fmt.Errorf("--lease must be a positive duration")
+		}
+	}
+
+	limits := pollingLimits{Floor: 15 * time.Minute, Ceiling: 24 * time.Hour}
+	if value, ok := flags["min-interval"]; ok {
+		limits.Floor, err = time.ParseDuration(value)
+		if err != nil || limits.Floor < 0 {
+			return fmt.Errorf("--min-interval must be a duration")
+		}
+	}
+	if value, ok := flags["max-interval"]; ok {
+		limits.Ceiling, err = time.ParseDuration(value)
+		if err != nil || limits.Ceiling < limits.Floor {
+			return fmt.Errorf("--max-interval must be a duration no shorter than --min-interval")
+		}
+	}
+
+	fmt.Printf("Collecting feeds every %s with %d workers\n", timeBetweenReqs, workers)
+
+	// each worker scrapes and stores one feed at a time, so a slow host only
+	// holds up its own worker. idle holds a token per free worker.
+	jobs := make(chan database.Feed, workers)
+	idle := make(chan struct{}, workers)
+	for range workers {
+		idle <- struct{}{}
+		go func() {
+			for feed := range jobs {
+				if err := scrapeFeed(context.Background(), s, feed, limits); err != nil {
+					fmt.Printf("error scraping feed %s: %v\n", feed.Name, err)
+				}
+				idle <- struct{}{}
+			}
+		}()
+	}
+
 	ticker := time.NewTicker(timeBetweenReqs)
 	for ; ; <-ticker.C {
-		if err := scrapeFeeds(s); err != nil {
-			fmt.Printf("error scraping feeds: %v", err)
+		// only claim feeds a worker can start on right away, so no claim
+		// waits in a queue while its lease runs out
+		free := min(batch, len(idle))
+		if free == 0 {
+			continue
+		}
+
+		// claimed rows are skipped by other agg processes until the lease
+		// expires or the feed is marked fetched
+		now := time.Now().UTC()
+		feeds, err := s.db.ClaimFeedsToFetch(context.Background(), database.ClaimFeedsToFetchParams{
+			LastFetchedAt: sql.NullTime{Time: now.Add(-timeBetweenReqs), Valid: true},
+			Limit:         int32(free),
+			ClaimedUntil:  sql.NullTime{Time: now.Add(lease), Valid: true},
+		})
+		if err != nil {
+			fmt.Printf("error claiming feeds to fetch: %v\n", err)
+			continue
+		}
+
+		for _, feed := range feeds {
+			<-idle
+			jobs <- feed
 		}
 	}
 }
 
 func HandlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 2 {
-		return fmt.Errorf("addfeed command needs two arguments: addfeed <name> <url>")
+	args, flags, err := cmd.parseFlags()
+	if err != nil {
+		return err
+	}
+
+	// the feed name used to come first; keep accepting that form
+	name := flags["name"]
+	switch {
+	case len(args) == 2 && name == "":
+		name = args[0]
+		args = args[1:]
+	case len(args) != 1:
+		return fmt.Errorf("addfeed command needs a url: addfeed <url> [--name <name>]")
 	}
 
-	name := cmd.args[0]
-	url := cmd.args[1]
 	userID := user.ID
 
+	rawURL, err := normalizeFeedURL(args[0])
+	if err != nil {
+		return err
+	}
+
 	ctx := context.Background()
-	_, err := s.db.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: name, Url: url, UserID: userID})
+	candidates, rssFeed, err := resolveFeedURL(ctx, s, rawURL)
+	if err != nil {
+		return err
+	}
+	if len(candidates) > 1 {
+		fmt.Printf("%s offers several feeds:\n", rawURL)
+		for _, candidate := range candidates {
+			fmt.Printf(" * %s\n", candidate)
+		}
+		return fmt.Errorf("pick one and run again: addfeed <url> [--name <name>]")
+	}
+
+	url, err := normalizeFeedURL(candidates[0])
+	if err != nil {
+		return err
+	}
+	canonical, err := canonicalFeedURL(url)
+	if err != nil {
+		return err
+	}
+
+	existing, err := getFeedByAnyURL(ctx, s, url)
+	if err == nil {
+		fmt.Printf("Feed already exists as %s (%s)\n", existing.Name, existing.Url)
+		return HandlerFollow(s, command{name: "follow", args: []string{existing.Url}}, user)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("error looking up feed: %w", err)
+	}
+
+	if rssFeed == nil {
+		fmt.Printf("Found feed at %s\n", url)
+		result, err := fetchFeed(ctx, s, url, feedValidators{})
+		if err != nil {
+			return fmt.Errorf("error fetching feed: %w", err)
+		}
+		rssFeed = result.Feed
+	}
+
+	printFeedPreview(url, rssFeed)
+
+	if name == "" {
+		name = strings.TrimSpace(rssFeed.Channel.Title)
+		if name == "" {
+			return fmt.Errorf("feed has no title, give it a name: addfeed <url> --name <name>")
+		}
+	}
+
+	_, err = s.db.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: name, Url: url, UserID: userID, CanonicalUrl: sql.NullString{String: canonical, Valid: true}})
 	if err != nil {
 		return fmt.Errorf("error creating feed: %w", err)
 	}
@@ -301,6 +675,25 @@ func HandlerAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
+func printFeedPreview(url string, rssFeed *RSSFeed) {
+	fmt.Printf("Title: %s\n", rssFeed.Channel.Title)
+	fmt.Printf("URL: %s\n", url)
+	fmt.Printf("Items: %d\n", len(rssFeed.Channel.Item))
+
+	var newest time.Time
+	for _, item := range rssFeed.Channel.Item {
+		if published, err := parseFeedDate(item.PubDate); err == nil && published.After(newest) {
+			newest = published
+		}
+	}
+	if newest.IsZero() {
+		fmt.Println("Newest item: unknown")
+	} else {
+		fmt.Printf("Newest item: %s\n", newest.Format(time.RFC1123))
+	}
+	fmt.Println(strings.Repeat("-", 50))
+}
+
 func HandlerFeeds(s *state, cmd command) error {
 	if len(cmd.args) != 0 {
 		return fmt.Errorf("feeds command doesn't take any arguments: feeds")
@@ -320,11 +713,184 @@ func HandlerFeeds(s *state, cmd command) error {
 		fmt.Printf("%s\n", feed.Name)
 		fmt.Printf("URL: %s\n", feed.Url)
 		fmt.Printf("Added by: %s\n", username)
+		fmt.Printf("Health: %s\n", feedHealth(feed))
 		fmt.Println(strings.Repeat("-", 50))
 	}
 	return nil
 }
 
+func feedHealth(feed database.GetFeedsRow) string {
+	status := "no http status"
+	if feed.LastStatusCode.Valid {
+		status = fmt.Sprintf("last status %d", feed.LastStatusCode.Int32)
+	}
+
+	switch {
+	case feed.DeadAt.Valid:
+		return fmt.Sprintf("dead since %s (%s)", feed.DeadAt.Time.Format(time.RFC1123), status)
+	case feed.ConsecutiveFailures > 0:
+		return fmt.Sprintf("failing, %d consecutive failures (%s)", feed.ConsecutiveFailures, status)
+	default:
+		return fmt.Sprintf("ok (%s)", status)
+	}
+}
+
+func HandlerFeedStatus(s *state, cmd command) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("feedstatus command doesn't take any arguments: feedstatus")
+	}
+
+	ctx := context.Background()
+	feeds, err := s.db.GetFailingFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting failing feeds from db: %w", err)
+	}
+
+	if len(feeds) == 0 {
+		fmt.Println("no failing feeds")
+		return nil
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("%s\n", feed.Name)
+		fmt.Printf("URL: %s\n", feed.Url)
+		if feed.LastStatusCode.Valid {
+			fmt.Printf("Failures: %d in a row (last status %d)\n", feed.ConsecutiveFailures, feed.LastStatusCode.Int32)
+		} else {
+			fmt.Printf("Failures: %d in a row\n", feed.ConsecutiveFailures)
+		}
+		if feed.LastError.Valid {
+			fmt.Printf("Last error: %s\n", feed.LastError.String)
+		}
+		if feed.DeadAt.Valid {
+			fmt.Printf("Dead since: %s\n", feed.DeadAt.Time.Format(time.RFC1123))
+		} else if feed.NextFetchAt.Valid {
+			fmt.Printf("Next retry: %s\n", feed.NextFetchAt.Time.Format(time.RFC1123))
+		}
+		fmt.Println(strings.Repeat("-", 50))
+	}
+	return nil
+}
+
+func HandlerSchedule(s *state, cmd command) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("schedule command doesn't take any arguments: schedule")
+	}
+
+	ctx := context.Background()
+	feeds, err := s.db.GetFeedSchedules(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting feed schedules from db: %w", err)
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("%s\n", feed.Name)
+		fmt.Printf("URL: %s\n", feed.Url)
+
+		if feed.PostingIntervalSeconds.Valid {
+			cadence := time.Duration(feed.PostingIntervalSeconds.Int32) * time.Second
+			fmt.Printf("Cadence: a post every %s\n", cadence)
+		} else {
+			fmt.Println("Cadence: not enough posts yet")
+		}
+		if feed.MinIntervalSeconds.Valid {
+			hint := time.Duration(feed.MinIntervalSeconds.Int32) * time.Second
+			fmt.Printf("Publisher minimum interval: %s\n", hint)
+		}
+
+		if feed.LastFetchedAt.Valid {
+			fmt.Printf("Last fetched: %s\n", feed.LastFetchedAt.Time.Format(time.RFC1123))
+		}
+		switch {
+		case feed.DeadAt.Valid:
+			fmt.Println("Next due: never, feed is dead")
+		case feed.NextFetchAt.Valid:
+			fmt.Printf("Next due: %s\n", feed.NextFetchAt.Time.Format(time.RFC1123))
+		default:
+			fmt.Println("Next due: next agg tick")
+		}
+		fmt.Println(strings.Repeat("-", 50))
+	}
+	return nil
+}
+
+// HandlerDedupeFeeds merges feeds whose urls share a canonical form into the
+// oldest of them, moving follows and posts over, and fills canonical_url for
+// every feed.
+func HandlerDedupeFeeds(s *state, cmd command) error {
+	if len(cmd.args) != 0 {
+		return fmt.Errorf("dedupefeeds command doesn't take any arguments: dedupefeeds")
+	}
+
+	ctx := context.Background()
+	feeds, err := s.db.ListFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting feeds from db: %w", err)
+	}
+
+	groups := map[string][]database.Feed{}
+	order := []string{}
+	for _, feed := range feeds {
+		canonical, err := canonicalFeedURL(feed.Url)
+		if err != nil {
+			fmt.Printf("Skipping %s: %v\n", feed.Name, err)
+			continue
+		}
+		if _, ok := groups[canonical]; !ok {
+			order = append(order, canonical)
+		}
+		groups[canonical] = append(groups[canonical], feed)
+	}
+
+	merged := 0
+	for _, canonical := range order {
+		group := groups[canonical]
+		if err := mergeFeeds(ctx, s, canonical, group[0], group[1:]); err != nil {
+			return fmt.Errorf("error merging feeds into %s: %w", group[0].Name, err)
+		}
+		for _, duplicate := range group[1:] {
+			fmt.Printf("Merged %s (%s) into %s (%s)\n", duplicate.Name, duplicate.Url, group[0].Name, group[0].Url)
+		}
+		merged += len(group) - 1
+	}
+
+	fmt.Printf("%d duplicate feeds merged\n", merged)
+	return nil
+}
+
+// mergeFeeds moves the follows and posts of duplicates to keeper, deletes
+// the duplicates and stores the keeper's canonical url, all or nothing.
+func mergeFeeds(ctx context.Context, s *state, canonical string, keeper database.Feed, duplicates []database.Feed) error {
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := s.db.WithTx(tx)
+	for _, duplicate := range duplicates {
+		if err = qtx.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{ToFeedID: keeper.ID, FromFeedID: duplicate.ID}); err != nil {
+			return fmt.Errorf("error moving follows: %w", err)
+		}
+		if err = qtx.MovePosts(ctx, database.MovePostsParams{ToFeedID: keeper.ID, FromFeedID: duplicate.ID}); err != nil {
+			return fmt.Errorf("error moving posts: %w", err)
+		}
+		// follows and posts the keeper already had go with the duplicate
+		if err = qtx.DeleteFeed(ctx, duplicate.ID); err != nil {
+			return fmt.Errorf("error deleting duplicate feed: %w", err)
+		}
+	}
+
+	if err = qtx.SetFeedCanonicalURL(ctx, database.SetFeedCanonicalURLParams{
+		ID:           keeper.ID,
+		CanonicalUrl: sql.NullString{String: canonical, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("error setting canonical url: %w", err)
+	}
+
+	return tx.Commit()
+}
+
 func HandlerFollow(s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("follow command needs one argument: follow <url>")
@@ -334,7 +900,7 @@ func HandlerFollow(s *state, cmd command, user database.User) error {
 
 	ctx := context.Background()
 
-	feed, err := s.db.GetFeedByURL(ctx, url)
+	feed, err := getFeedByAnyURL(ctx, s, url)
 	if err != nil {
 		return fmt.Errorf("error getting feed from db by url: %w", err)
 	}
@@ -363,7 +929,7 @@ func HandlerFollowing(s *state, cmd command, user database.User) error {
 
 	fmt.Printf("%s is following:\n", s.config.CurrentUserName)
 	for _, feedFollow := range feedFollows {
-		fmt.Printf(" * %s\n", feedFollow.FeedName)
+		fmt.Printf(" * %s (%d unread)\n", feedFollow.FeedName, feedFollow.UnreadCount)
 	}
 	return nil
 }
@@ -375,7 +941,7 @@ func HandlerUnfollow(s *state, cmd command, user database.User) error {
 
 	ctx := context.Background()
 	url := cmd.args[0]
-	feed, err := s.db.GetFeedByURL(ctx, url)
+	feed, err := getFeedByAnyURL(ctx, s, url)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("feed not in database")
@@ -383,7 +949,7 @@ func HandlerUnfollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("error getting feed by url: %w", err)
 	}
 
-	if err = s.db.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: user.ID, Url: url}); err != nil {
+	if err = s.db.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: user.ID, Url: feed.Url}); err != nil {
 		return fmt.Errorf("error deleting feed follow from url: %w", err)
 	}
 
@@ -395,12 +961,16 @@ func HandlerUnfollow(s *state, cmd command, user database.User) error {
 func HandlerBrowse(s *state, cmd command, user database.User) error {
 	var limit int32 = 2
 
-	if len(cmd.args) > 1 {
-		return fmt.Errorf("browse takes at most one arguemnt: browse <limit>")
+	args, flags, err := cmd.parseFlags("full", "all")
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("browse takes at most one arguemnt: browse <limit> [--author <name>] [--category <name>] [--all] [--full]")
 	}
 
-	if len(cmd.args) == 1 {
-		parsedLimit, err := strconv.Atoi(cmd.args[0])
+	if len(args) == 1 {
+		parsedLimit, err := strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("error converting arg into int")
 		}
@@ -408,16 +978,24 @@ func HandlerBrowse(s *state, cmd command, user database.User) error {
 	}
 
 	ctx := context.Background()
+	author, category := flags["author"], flags["category"]
 	posts, err := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  limit,
+		UserID:      user.ID,
+		IncludeRead: flags["all"] == "true",
+		Author:      sql.NullString{String: author, Valid: author != ""},
+		Category:    sql.NullString{String: category, Valid: category != ""},
+		PostLimit:   limit,
 	})
 	if err != nil {
 		return fmt.Errorf("error getting posts for user: %w", err)
 	}
 
 	if len(posts) == 0 {
-		fmt.Println("no posts found")
+		if flags["all"] == "true" {
+			fmt.Println("no posts found")
+		} else {
+			fmt.Println("no unread posts found, use --all to include read posts")
+		}
 		return nil
 	}
 
@@ -431,13 +1009,43 @@ func HandlerBrowse(s *state, cmd command, user database.User) error {
 			fmt.Println("Title: [No title]")
 		}
 		fmt.Printf("Feed: %s\n", post.FeedName)
+		fmt.Printf("ID: %s\n", post.ID)
+		if post.IsRead {
+			fmt.Println("Status: read")
+		}
 
-		if post.PublishedAt.Valid {
+		if post.PublishedAt.Valid && post.DateUnknown {
+			fmt.Printf("First seen: %s\n", post.PublishedAt.Time.Format(time.RFC1123))
+		} else if post.PublishedAt.Valid {
 			fmt.Printf("Published: %s\n", post.PublishedAt.Time.Format(time.RFC1123))
 		}
 
+		if post.Revision > 1 {
+			fmt.Printf("Edited: revision %d, last updated %s\n", post.Revision, post.UpdatedAt.Format(time.RFC1123))
+		}
+
 		fmt.Printf("URL: %s\n", post.Url)
 
+		authors, err := s.db.GetAuthorsForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting authors for post: %w", err)
+		}
+		if len(authors) > 0 {
+			fmt.Printf("Authors: %s\n", strings.Join(authors, ", "))
+		}
+
+		categories, err := s.db.GetCategoriesForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting categories for post: %w", err)
+		}
+		if len(categories) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(categories, ", "))
+		}
+
+		if post.CommentsUrl.Valid {
+			fmt.Printf("Comments: %s\n", post.CommentsUrl.String)
+		}
+
 		if post.Description.Valid && post.Description.String != "" {
 			descriptionPreview := post.Description.String
 			if len(descriptionPreview) > 100 {
@@ -446,7 +1054,223 @@ func HandlerBrowse(s *state, cmd command, user database.User) error {
 			fmt.Printf("Description: %s\n", descriptionPreview)
 		}
 
+		enclosures, err := s.db.GetEnclosuresForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting enclosures for post: %w", err)
+		}
+		for _, enclosure := range enclosures {
+			fmt.Printf("Attachment: %s\n", formatEnclosure(enclosure.Url, enclosure.MimeType, enclosure.Length, enclosure.DurationSeconds))
+		}
+
+		if flags["full"] == "true" {
+			switch {
+			case post.FullContent.Valid:
+				fmt.Printf("Content:\n%s\n", htmlToText(post.FullContent.String))
+			case post.ContentText.Valid:
+				fmt.Printf("Content:\n%s\n", post.ContentText.String)
+			}
+		}
+
 		fmt.Println(strings.Repeat("-", 50))
 	}
 	return nil
 }
+
+// saveFullContent downloads a new post's page and stores the extracted
+// article. Failures are only reported: the feed's own content stays usable.
+func saveFullContent(ctx context.Context, s *state, post database.Post) {
+	article, err := fetchArticle(ctx, s, post.Url)
+	if err != nil {
+		fmt.Printf("Warning: couldn't extract full content of %s: %v\n", post.Url, err)
+		return
+	}
+	if err := s.db.UpdatePostFullContent(ctx, database.UpdatePostFullContentParams{
+		ID:          post.ID,
+		FullContent: sql.NullString{String: article, Valid: true},
+	}); err != nil {
+		fmt.Printf("Error saving full content of %s: %v\n", post.Url, err)
+	}
+}
+
+func HandlerFullContent(s *state, cmd command) error {
+	if len(cmd.args) != 2 || (cmd.args[1] != "on" && cmd.args[1] != "off") {
+		return fmt.Errorf("fullcontent command needs a feed url and on or off: fullcontent <url> <on|off>")
+	}
+
+	ctx := context.Background()
+	feed, err := getFeedByAnyURL(ctx, s, cmd.args[0])
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("feed %s not found", cmd.args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("error looking up feed: %w", err)
+	}
+
+	enabled := cmd.args[1] == "on"
+	if err := s.db.SetFeedFetchFullContent(ctx, database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: enabled,
+	}); err != nil {
+		return fmt.Errorf("error updating feed: %w", err)
+	}
+
+	if enabled {
+		fmt.Printf("New posts from %s will be downloaded in full\n", feed.Name)
+	} else {
+		fmt.Printf("Full content download turned off for %s\n", feed.Name)
+	}
+	return nil
+}
+
+func HandlerEnclosures(s *state, cmd command, user database.User) error {
+	var limit int32 = 10
+
+	if len(cmd.args) > 1 {
+		return fmt.Errorf("enclosures takes at most one argument: enclosures <limit>")
+	}
+
+	if len(cmd.args) == 1 {
+		parsedLimit, err := strconv.Atoi(cmd.args[0])
+		if err != nil {
+			return fmt.Errorf("error converting arg into int")
+		}
+		limit = int32(parsedLimit)
+	}
+
+	ctx := context.Background()
+	enclosures, err := s.db.GetEnclosuresForUser(ctx, database.GetEnclosuresForUserParams{
+		UserID: user.ID,
+		Limit:  limit,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting enclosures for user: %w", err)
+	}
+
+	if len(enclosures) == 0 {
+		fmt.Println("no media found in the feeds you follow")
+		return nil
+	}
+
+	for _, enclosure := range enclosures {
+		title := "[No title]"
+		if enclosure.PostTitle.Valid {
+			title = enclosure.PostTitle.String
+		}
+		fmt.Printf("%s - %s\n", enclosure.FeedName, title)
+		if enclosure.PublishedAt.Valid {
+			fmt.Printf("  Published: %s\n", enclosure.PublishedAt.Time.Format(time.RFC1123))
+		}
+		fmt.Printf("  Download: %s\n", formatEnclosure(enclosure.Url, enclosure.MimeType, enclosure.Length, enclosure.DurationSeconds))
+		if enclosure.ImageUrl.Valid {
+			fmt.Printf("  Image: %s\n", enclosure.ImageUrl.String)
+		}
+	}
+	return nil
+}
+
+// followedPosts finds the posts a user's feeds published under the given
+// post id or url.
+func followedPosts(ctx context.Context, s *state, user database.User, idOrURL string) ([]database.Post, error) {
+	posts, err := s.db.GetFollowedPostsByIDOrURL(ctx, database.GetFollowedPostsByIDOrURLParams{
+		UserID:  user.ID,
+		IDOrUrl: idOrURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error looking up post: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil, fmt.Errorf("no post %s in the feeds you follow", idOrURL)
+	}
+	return posts, nil
+}
+
+func HandlerRead(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("read command needs a post id or url: read <post>")
+	}
+
+	ctx := context.Background()
+	posts, err := followedPosts(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		if err := s.db.MarkPostRead(ctx, database.MarkPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UserID:    user.ID,
+			PostID:    post.ID,
+		}); err != nil {
+			return fmt.Errorf("error marking post as read: %w", err)
+		}
+		fmt.Printf("Marked as read: %s\n", post.Title.String)
+	}
+	return nil
+}
+
+func HandlerUnread(s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("unread command needs a post id or url: unread <post>")
+	}
+
+	ctx := context.Background()
+	posts, err := followedPosts(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		if _, err := s.db.MarkPostUnread(ctx, database.MarkPostUnreadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		}); err != nil {
+			return fmt.Errorf("error marking post as unread: %w", err)
+		}
+		fmt.Printf("Marked as unread: %s\n", post.Title.String)
+	}
+	return nil
+}
+
+func HandlerMarkAllRead(s *state, cmd command, user database.User) error {
+	args, flags, err := cmd.parseFlags()
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("markallread takes no arguments: markallread [--feed <url>] [--before <date>]")
+	}
+
+	ctx := context.Background()
+	params := database.MarkAllPostsReadParams{
+		ReadAt: time.Now(),
+		UserID: user.ID,
+	}
+
+	if feedURL, ok := flags["feed"]; ok {
+		feed, err := getFeedByAnyURL(ctx, s, feedURL)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed %s not found", feedURL)
+		}
+		if err != nil {
+			return fmt.Errorf("error looking up feed: %w", err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	if value, ok := flags["before"]; ok {
+		before, err := parseFeedDate(value)
+		if err != nil {
+			return fmt.Errorf("invalid --before date: %w", err)
+		}
+		params.Before = sql.NullTime{Time: before.UTC(), Valid: true}
+	}
+
+	marked, err := s.db.MarkAllPostsRead(ctx, params)
+	if err != nil {
+		return fmt.Errorf("error marking posts as read: %w", err)
+	}
+
+	fmt.Printf("Marked %d posts as read\n", marked)
+	return nil
+}
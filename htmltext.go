@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// paragraphElements are separated from the surrounding text by a blank line.
+var paragraphElements = map[string]bool{
+	"p":          true,
+	"div":        true,
+	"h1":         true,
+	"h2":         true,
+	"h3":         true,
+	"h4":         true,
+	"h5":         true,
+	"h6":         true,
+	"blockquote": true,
+	"pre":        true,
+	"ul":         true,
+	"ol":         true,
+	"dl":         true,
+	"table":      true,
+	"figure":     true,
+	"article":    true,
+	"section":    true,
+	"header":     true,
+	"footer":     true,
+	"aside":      true,
+	"details":    true,
+	"hr":         true,
+}
+
+// lineElements start on a line of their own.
+var lineElements = map[string]bool{
+	"li":         true,
+	"dt":         true,
+	"dd":         true,
+	"tr":         true,
+	"caption":    true,
+	"figcaption": true,
+	"summary":    true,
+}
+
+// textRenderer accumulates text, collapsing whitespace the way a browser
+// does and deferring line breaks until the next piece of text, so runs of
+// block elements never produce more than one blank line.
+type textRenderer struct {
+	out          strings.Builder
+	pendingLines int
+	pendingSpace bool
+	preformatted int
+}
+
+func (r *textRenderer) text(s string) {
+	if r.preformatted > 0 {
+		r.flush()
+		r.out.WriteString(s)
+		return
+	}
+
+	for i, word := range strings.Fields(s) {
+		if i > 0 || startsWithSpace(s) {
+			r.pendingSpace = true
+		}
+		r.flush()
+		r.out.WriteString(word)
+	}
+	if endsWithSpace(s) {
+		r.pendingSpace = true
+	}
+}
+
+// lines asks for the next text to start after n line breaks.
+func (r *textRenderer) lines(n int) {
+	r.pendingLines = max(r.pendingLines, n)
+}
+
+func (r *textRenderer) flush() {
+	if r.out.Len() == 0 {
+		r.pendingLines, r.pendingSpace = 0, false
+		return
+	}
+	if r.pendingLines > 0 {
+		r.out.WriteString(strings.Repeat("\n", r.pendingLines))
+	} else if r.pendingSpace {
+		r.out.WriteString(" ")
+	}
+	r.pendingLines, r.pendingSpace = 0, false
+}
+
+// startsWithSpace and endsWithSpace agree with strings.Fields, which also
+// splits on non-breaking spaces.
+func startsWithSpace(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return s != "" && unicode.IsSpace(r)
+}
+
+func endsWithSpace(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return s != "" && unicode.IsSpace(r)
+}
+
+// htmlToText renders HTML as readable plain text: paragraphs are separated
+// by blank lines, list items are bulleted and links are numbered, with their
+// urls listed as footnotes at the end.
+func htmlToText(fragment string) string {
+	return renderHTMLText(fragment, true)
+}
+
+// htmlToSummaryText renders HTML like htmlToText but without link footnotes,
+// for short texts such as descriptions that are shown as previews.
+func htmlToSummaryText(fragment string) string {
+	return renderHTMLText(fragment, false)
+}
+
+func renderHTMLText(fragment string, footnotes bool) string {
+	r := textRenderer{}
+	links := []string{}
+	linkNumbers := map[string]int{}
+	type openLink struct {
+		href string
+		text int // length of the output when the link started
+	}
+	openLinks := []openLink{}
+	skipping := ""
+	depth := 0
+
+	for _, token := range tokenizeHTML(fragment) {
+		if skipping != "" {
+			switch {
+			case token.Type == htmlStartTag && token.Data == skipping:
+				depth++
+			case token.Type == htmlEndTag && token.Data == skipping:
+				depth--
+				if depth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+
+		switch token.Type {
+		case htmlText:
+			r.text(token.Data)
+
+		case htmlStartTag, htmlSelfClosingTag:
+			name := token.Data
+			if droppedElements[name] {
+				if token.Type == htmlStartTag && !voidElements[name] {
+					skipping, depth = name, 1
+				}
+				continue
+			}
+
+			switch {
+			case name == "br":
+				r.lines(1)
+			case name == "li":
+				r.lines(1)
+				r.flush()
+				r.out.WriteString("- ")
+			case paragraphElements[name]:
+				r.lines(2)
+			case lineElements[name]:
+				r.lines(1)
+			case name == "td" || name == "th":
+				r.pendingSpace = true
+			case name == "img":
+				if alt, _ := token.attr("alt"); strings.TrimSpace(alt) != "" {
+					r.text(" [image: " + strings.TrimSpace(alt) + "] ")
+				}
+			case name == "a" && token.Type == htmlStartTag:
+				href, _ := token.attr("href")
+				openLinks = append(openLinks, openLink{href: strings.TrimSpace(href), text: r.out.Len()})
+			}
+			if name == "pre" && token.Type == htmlStartTag {
+				r.preformatted++
+			}
+
+		case htmlEndTag:
+			name := token.Data
+			switch {
+			case paragraphElements[name]:
+				r.lines(2)
+			case lineElements[name] || name == "li":
+				r.lines(1)
+			case name == "a" && len(openLinks) > 0:
+				link := openLinks[len(openLinks)-1]
+				openLinks = openLinks[:len(openLinks)-1]
+				text := strings.TrimSpace(r.out.String()[link.text:])
+				if !footnotes || !footnoteWorthy(link.href, text) {
+					continue
+				}
+				n, ok := linkNumbers[link.href]
+				if !ok {
+					links = append(links, link.href)
+					n = len(links)
+					linkNumbers[link.href] = n
+				}
+				fmt.Fprintf(&r.out, " [%d]", n)
+			}
+			if name == "pre" && r.preformatted > 0 {
+				r.preformatted--
+			}
+		}
+	}
+
+	text := strings.TrimSpace(r.out.String())
+	if len(links) > 0 {
+		notes := strings.Builder{}
+		for i, link := range links {
+			fmt.Fprintf(&notes, "\n[%d] %s", i+1, link)
+		}
+		text += "\n" + notes.String()
+	}
+	return text
+}
+
+// footnoteWorthy reports whether a link's url adds something to its text.
+// In-page anchors, unsafe urls and links whose text is the url itself are
+// left without a footnote.
+func footnoteWorthy(href, text string) bool {
+	if href == "" || strings.HasPrefix(href, "#") || !safeURL(href) {
+		return false
+	}
+	return text != href && text != strings.TrimPrefix(strings.TrimPrefix(href, "https://"), "http://")
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "Hello world", "Hello world"},
+		{"whitespace collapsed", "  Hello \n\t  world  ", "Hello world"},
+		{"paragraphs", "<p>One</p><p>Two</p>", "One\n\nTwo"},
+		{"runs of blocks make one blank line", "<div><p>One</p></div>\n\n<div><div><p>Two</p></div></div>", "One\n\nTwo"},
+		{"line breaks", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"headings", "<h1>Title</h1>Body", "Title\n\nBody"},
+		{"list items", "<ul><li>a</li><li>b</li></ul>", "- a\n- b"},
+		{"preformatted text kept", "<pre>a  b\n  c</pre>", "a  b\n  c"},
+		{"entities", "caf&eacute; &amp; &lt;tag&gt; &quot;q&quot;", `café & <tag> "q"`},
+		{"non-breaking space separates words", "a&nbsp;b", "a b"},
+		{"bare less than", "a < b and c<d", "a < b and c<d"},
+		{"scripts and styles dropped", "<style>p{}</style>x<script>var a = 1 < 2;</script>y", "xy"},
+		{"inline elements don't break words", "<b>bold</b> and <i>it</i>alic", "bold and italic"},
+		{"image alt text", `<img src="a.png" alt="A cat">`, "[image: A cat]"},
+		{"link footnote", `Read <a href="https://e.com/post">the post</a>.`, "Read the post [1].\n\n[1] https://e.com/post"},
+		{
+			"repeated links share a footnote",
+			`<a href="https://e.com/a">a</a> <a href="https://e.com/b">b</a> <a href="https://e.com/a">again</a>`,
+			"a [1] b [2] again [1]\n\n[1] https://e.com/a\n[2] https://e.com/b",
+		},
+		{"link whose text is its url", `<a href="https://e.com/">e.com/</a>`, "e.com/"},
+		{"in-page anchor", `<a href="#top">top</a>`, "top"},
+		{"javascript link", `<a href="javascript:alert(1)">click</a>`, "click"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.input); got != tt.want {
+				t.Errorf("htmlToText(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToSummaryText(t *testing.T) {
+	input := `<p>Read <a href="https://e.com/post">the post</a>.</p><p>More</p>`
+	want := "Read the post.\n\nMore"
+	if got := htmlToSummaryText(input); got != want {
+		t.Errorf("htmlToSummaryText(%q) = %q, want %q", input, got, want)
+	}
+}
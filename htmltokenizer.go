@@ -0,0 +1,252 @@
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+type htmlTokenType int
+
+const (
+	htmlText htmlTokenType = iota
+	htmlStartTag
+	htmlEndTag
+	htmlSelfClosingTag
+	htmlComment
+)
+
+type htmlAttr struct {
+	Key string
+	Val string
+}
+
+// htmlToken is a piece of an HTML document. Data holds the lowercased tag
+// name for tags, and the entity-decoded text for text and comments.
+type htmlToken struct {
+	Type  htmlTokenType
+	Data  string
+	Attrs []htmlAttr
+}
+
+func (t htmlToken) attr(key string) (string, bool) {
+	for _, a := range t.Attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// rawTextElements hold text up to their end tag, markup included.
+var rawTextElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+	"title":    true,
+}
+
+// tokenizeHTML splits an HTML document or fragment into tokens. It is lenient
+// the way browsers are: a "<" that doesn't open a tag is text, and an
+// unterminated tag turns the rest of the input into text.
+func tokenizeHTML(s string) []htmlToken {
+	tokens := []htmlToken{}
+	text := strings.Builder{}
+
+	flushText := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, htmlToken{Type: htmlText, Data: html.UnescapeString(text.String())})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] != '<' || i+1 >= len(s) {
+			text.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		next := s[i+1]
+		switch {
+		case strings.HasPrefix(s[i:], "<!--"):
+			end := strings.Index(s[i+4:], "-->")
+			flushText()
+			if end < 0 {
+				tokens = append(tokens, htmlToken{Type: htmlComment, Data: s[i+4:]})
+				return tokens
+			}
+			tokens = append(tokens, htmlToken{Type: htmlComment, Data: s[i+4 : i+4+end]})
+			i += 4 + end + 3
+
+		case next == '!' || next == '?':
+			// doctype, cdata or processing instruction
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				text.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			if inner, ok := strings.CutPrefix(s[i:i+end], "<![CDATA["); ok {
+				flushText()
+				tokens = append(tokens, htmlToken{Type: htmlText, Data: strings.TrimSuffix(inner, "]]")})
+			}
+			i += end + 1
+
+		case next == '/' && i+2 < len(s) && isASCIILetter(s[i+2]):
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				text.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			name, _, _ := strings.Cut(strings.TrimSpace(s[i+2:i+end]), " ")
+			flushText()
+			tokens = append(tokens, htmlToken{Type: htmlEndTag, Data: strings.ToLower(name)})
+			i += end + 1
+
+		case isASCIILetter(next):
+			token, length, ok := parseHTMLTag(s[i:])
+			if !ok {
+				text.WriteString(s[i:])
+				i = len(s)
+				continue
+			}
+			flushText()
+			tokens = append(tokens, token)
+			i += length
+
+			if token.Type == htmlStartTag && rawTextElements[token.Data] {
+				end := indexEndTag(s[i:], token.Data)
+				if end < 0 {
+					end = len(s) - i
+				}
+				raw := s[i : i+end]
+				if token.Data == "title" || token.Data == "textarea" {
+					raw = html.UnescapeString(raw)
+				}
+				if raw != "" {
+					tokens = append(tokens, htmlToken{Type: htmlText, Data: raw})
+				}
+				i += end
+			}
+
+		default:
+			text.WriteByte(s[i])
+			i++
+		}
+	}
+
+	flushText()
+	return tokens
+}
+
+// parseHTMLTag parses a start tag at the beginning of s and returns it with
+// the number of bytes it spans.
+func parseHTMLTag(s string) (htmlToken, int, bool) {
+	i := 1
+	start := i
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	token := htmlToken{Type: htmlStartTag, Data: strings.ToLower(s[start:i])}
+
+	for i < len(s) {
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return htmlToken{}, 0, false
+		}
+
+		switch {
+		case s[i] == '>':
+			return token, i + 1, true
+		case strings.HasPrefix(s[i:], "/>"):
+			token.Type = htmlSelfClosingTag
+			return token, i + 2, true
+		case s[i] == '/':
+			i++
+			continue
+		}
+
+		keyStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && !strings.HasPrefix(s[i:], "/>") {
+			i++
+		}
+		attr := htmlAttr{Key: strings.ToLower(s[keyStart:i])}
+
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return htmlToken{}, 0, false
+				}
+				attr.Val = html.UnescapeString(s[i+1 : i+1+end])
+				i += end + 2
+			} else {
+				valStart := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.Val = html.UnescapeString(s[valStart:i])
+			}
+		}
+
+		if attr.Key != "" {
+			token.Attrs = append(token.Attrs, attr)
+		}
+	}
+
+	return htmlToken{}, 0, false
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// indexEndTag returns the index of the first end tag for name in s, or -1.
+// The tag name is matched ASCII case-insensitively on the original bytes, so
+// the index is valid in s whatever its encoding.
+func indexEndTag(s, name string) int {
+	for i := 0; i+2+len(name) <= len(s); i++ {
+		if s[i] != '<' || s[i+1] != '/' || !equalFoldASCII(s[i+2:i+2+len(name)], name) {
+			continue
+		}
+		after := i + 2 + len(name)
+		if after == len(s) || isHTMLSpace(s[after]) || s[after] == '/' || s[after] == '>' {
+			return i
+		}
+	}
+	return -1
+}
+
+func equalFoldASCII(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if lowerASCII(a[i]) != lowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
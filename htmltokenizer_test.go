@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeHTMLRawText(t *testing.T) {
+	latin1 := strings.Repeat("\xe9", 10)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []htmlToken
+	}{
+		{
+			name:  "mixed case end tag",
+			input: "<script>x < y</SCRIPT><p>after</p>",
+			want: []htmlToken{
+				{Type: htmlStartTag, Data: "script"},
+				{Type: htmlText, Data: "x < y"},
+				{Type: htmlEndTag, Data: "script"},
+				{Type: htmlStartTag, Data: "p"},
+				{Type: htmlText, Data: "after"},
+				{Type: htmlEndTag, Data: "p"},
+			},
+		},
+		{
+			name:  "end tag with whitespace",
+			input: "<Style>a{}</sTyLe >b",
+			want: []htmlToken{
+				{Type: htmlStartTag, Data: "style"},
+				{Type: htmlText, Data: "a{}"},
+				{Type: htmlEndTag, Data: "style"},
+				{Type: htmlText, Data: "b"},
+			},
+		},
+		{
+			name:  "longer tag name is not the end tag",
+			input: "<script></scripts></script>",
+			want: []htmlToken{
+				{Type: htmlStartTag, Data: "script"},
+				{Type: htmlText, Data: "</scripts>"},
+				{Type: htmlEndTag, Data: "script"},
+			},
+		},
+		{
+			name:  "non-UTF-8 script",
+			input: "<script>" + latin1 + "</script><p>visible</p>",
+			want: []htmlToken{
+				{Type: htmlStartTag, Data: "script"},
+				{Type: htmlText, Data: latin1},
+				{Type: htmlEndTag, Data: "script"},
+				{Type: htmlStartTag, Data: "p"},
+				{Type: htmlText, Data: "visible"},
+				{Type: htmlEndTag, Data: "p"},
+			},
+		},
+		{
+			name:  "non-UTF-8 title",
+			input: "<title>caf\xe9</TITLE>text",
+			want: []htmlToken{
+				{Type: htmlStartTag, Data: "title"},
+				{Type: htmlText, Data: "caf\xe9"},
+				{Type: htmlEndTag, Data: "title"},
+				{Type: htmlText, Data: "text"},
+			},
+		},
+		{
+			name:  "unterminated non-UTF-8 script",
+			input: "<p>hello</p><script>" + latin1,
+			want: []htmlToken{
+				{Type: htmlStartTag, Data: "p"},
+				{Type: htmlText, Data: "hello"},
+				{Type: htmlEndTag, Data: "p"},
+				{Type: htmlStartTag, Data: "script"},
+				{Type: htmlText, Data: latin1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenizeHTML(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenizeHTML(%q) =\n%#v\nwant\n%#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonUTF8RawText(t *testing.T) {
+	latin1 := strings.Repeat("\xe9", 10)
+	pageURL, _ := url.Parse("https://example.com/post")
+
+	// used to panic with slice bounds out of range
+	extractArticle("<p>hello</p><script>"+latin1+"</script>", pageURL)
+
+	input := "<title>caf\xe9</title><p>still visible</p>"
+	if got := htmlToText(input); got != "still visible" {
+		t.Errorf("htmlToText(%q) = %q, want %q", input, got, "still visible")
+	}
+	if got := sanitizeHTML(input); got != "<p>still visible</p>" {
+		t.Errorf("sanitizeHTML(%q) = %q, want %q", input, got, "<p>still visible</p>")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IlMeloIl/RSS/internal/config"
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+	defaultMaxFeedBytes   = 10 << 20
+)
+
+// newHTTPClient builds the client shared by every feed download. The connect
+// timeout covers dialing and the TLS handshake; the read timeout caps the
+// whole request, body included.
+func newHTTPClient(cfg *config.Config) (*http.Client, error) {
+	connectTimeout, err := configDuration(cfg.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connect_timeout: %w", err)
+	}
+	readTimeout, err := configDuration(cfg.ReadTimeout, defaultReadTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read_timeout: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       readTimeout,
+		CheckRedirect: checkRedirect,
+	}, nil
+}
+
+func configDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive: %s", value)
+	}
+	return d, nil
+}
+
+func maxFeedBytes(cfg *config.Config) int64 {
+	if cfg.MaxFeedBytes > 0 {
+		return cfg.MaxFeedBytes
+	}
+	return defaultMaxFeedBytes
+}
+
+type redirectTraceKey struct{}
+
+// redirectTrace records where a request was permanently moved. The client
+// is shared, so the trace travels in the request context.
+type redirectTrace struct {
+	permanentURL string
+	temporary    bool
+}
+
+func withRedirectTrace(ctx context.Context) (context.Context, *redirectTrace) {
+	trace := &redirectTrace{}
+	return context.WithValue(ctx, redirectTraceKey{}, trace), trace
+}
+
+// checkRedirect follows up to 10 redirects. Only a chain that starts with
+// permanent redirects moves the feed.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+
+	trace, ok := req.Context().Value(redirectTraceKey{}).(*redirectTrace)
+	if !ok {
+		return nil
+	}
+	switch req.Response.StatusCode {
+	case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		if !trace.temporary {
+			trace.permanentURL = req.URL.String()
+		}
+	default:
+		trace.temporary = true
+	}
+	return nil
+}
+
+// acceptEncoding lists the compressions we decode ourselves.
+const acceptEncoding = "gzip, deflate, br"
+
+// decompressedBody undoes the response Content-Encoding.
+func decompressedBody(resp *http.Response) (io.Reader, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gzip body: %w", err)
+		}
+		return r, nil
+	case "deflate":
+		// deflate should be zlib-wrapped, but some servers send raw deflate
+		body := bufio.NewReader(resp.Body)
+		header, err := body.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			r, err := zlib.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("error reading deflate body: %w", err)
+			}
+			return r, nil
+		}
+		return flate.NewReader(body), nil
+	case "br":
+		return brotli.NewReader(resp.Body), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// readLimitedBody reads at most maxBytes of the decompressed body and fails
+// instead of truncating when the response is bigger.
+func readLimitedBody(resp *http.Response, maxBytes int64) ([]byte, error) {
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("response of %d bytes is larger than the %d bytes limit", resp.ContentLength, maxBytes)
+	}
+
+	body, err := decompressedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if int64(len(b)) > maxBytes {
+		return nil, fmt.Errorf("response is larger than the %d bytes limit", maxBytes)
+	}
+	return b, nil
+}
+
+type contentTypeError struct {
+	ContentType string
+}
+
+func (e *contentTypeError) Error() string {
+	return fmt.Sprintf("response is not a feed: content type %s", e.ContentType)
+}
+
+func (e *contentTypeError) isHTML() bool {
+	mt := mediaType(e.ContentType)
+	return mt == "text/html" || mt == "application/xhtml+xml"
+}
+
+// mediaType returns the lowercased media type without parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt, _, _ = strings.Cut(contentType, ";")
+	}
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// isNonFeedContentType reports media types that can never hold a feed. HTML
+// is not one of them: misconfigured servers label feeds text/html, so HTML
+// is only rejected once it fails to parse.
+func isNonFeedContentType(contentType string) bool {
+	mt := mediaType(contentType)
+	for _, prefix := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(mt, prefix) {
+			return true
+		}
+	}
+	switch mt {
+	case "application/pdf", "application/zip", "application/gzip", "application/javascript", "text/css":
+		return true
+	}
+	return false
+}
@@ -11,6 +11,9 @@ import (
 type Config struct {
 	DbURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	ConnectTimeout  string `json:"connect_timeout,omitempty"`
+	ReadTimeout     string `json:"read_timeout,omitempty"`
+	MaxFeedBytes    int64  `json:"max_feed_bytes,omitempty"`
 }
 
 const filename = ".gatorconfig.json"
@@ -95,7 +95,15 @@ const getFeedFollowsUser = `-- name: GetFeedFollowsUser :many
 SELECT 
 feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
 feeds.name AS feed_name,
-users.name AS user_name
+users.name AS user_name,
+(
+    SELECT COUNT(*) FROM posts
+    WHERE posts.feed_id = feed_follows.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+    )
+)::int AS unread_count
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 INNER JOIN users ON users.id = feed_follows.user_id
@@ -103,13 +111,14 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	FeedName    string
+	UserName    string
+	UnreadCount int32
 }
 
 func (q *Queries) GetFeedFollowsUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsUserRow, error) {
@@ -129,6 +138,7 @@ func (q *Queries) GetFeedFollowsUser(ctx context.Context, userID uuid.UUID) ([]G
 			&i.FeedID,
 			&i.FeedName,
 			&i.UserName,
+			&i.UnreadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -142,3 +152,22 @@ func (q *Queries) GetFeedFollowsUser(ctx context.Context, userID uuid.UUID) ([]G
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1
+WHERE feed_id = $2
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows WHERE feed_id = $1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
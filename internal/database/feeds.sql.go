@@ -7,31 +7,103 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = $3,
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE (last_fetched_at IS NULL OR last_fetched_at < $1)
+    AND (claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+    AND dead_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+    ORDER BY last_fetched_at NULLS FIRST, id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, last_status_code, consecutive_failures, dead_at, last_error, next_fetch_at, min_interval_seconds, skip_hours, skip_days, posting_interval_seconds, canonical_url, fetch_full_content
+`
+
+type ClaimFeedsToFetchParams struct {
+	LastFetchedAt sql.NullTime
+	Limit         int32
+	ClaimedUntil  sql.NullTime
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.LastFetchedAt, arg.Limit, arg.ClaimedUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.LastStatusCode,
+			&i.ConsecutiveFailures,
+			&i.DeadAt,
+			&i.LastError,
+			&i.NextFetchAt,
+			&i.MinIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PostingIntervalSeconds,
+			&i.CanonicalUrl,
+			&i.FetchFullContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, canonical_url)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, last_status_code, consecutive_failures, dead_at, last_error, next_fetch_at, min_interval_seconds, skip_hours, skip_days, posting_interval_seconds, canonical_url, fetch_full_content
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	Url          string
+	UserID       uuid.UUID
+	CanonicalUrl sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -42,6 +114,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.CanonicalUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -52,12 +125,123 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.LastStatusCode,
+		&i.ConsecutiveFailures,
+		&i.DeadAt,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.MinIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PostingIntervalSeconds,
+		&i.CanonicalUrl,
+		&i.FetchFullContent,
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getFailingFeeds = `-- name: GetFailingFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, last_status_code, consecutive_failures, dead_at, last_error, next_fetch_at, min_interval_seconds, skip_hours, skip_days, posting_interval_seconds, canonical_url, fetch_full_content
+FROM feeds
+WHERE consecutive_failures > 0
+ORDER BY dead_at NULLS FIRST, consecutive_failures DESC, name
+`
+
+func (q *Queries) GetFailingFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFailingFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.LastStatusCode,
+			&i.ConsecutiveFailures,
+			&i.DeadAt,
+			&i.LastError,
+			&i.NextFetchAt,
+			&i.MinIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PostingIntervalSeconds,
+			&i.CanonicalUrl,
+			&i.FetchFullContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedByCanonicalURL = `-- name: GetFeedByCanonicalURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, last_status_code, consecutive_failures, dead_at, last_error, next_fetch_at, min_interval_seconds, skip_hours, skip_days, posting_interval_seconds, canonical_url, fetch_full_content
+FROM feeds
+WHERE canonical_url = $1
+`
+
+func (q *Queries) GetFeedByCanonicalURL(ctx context.Context, canonicalUrl sql.NullString) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByCanonicalURL, canonicalUrl)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.LastStatusCode,
+		&i.ConsecutiveFailures,
+		&i.DeadAt,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.MinIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PostingIntervalSeconds,
+		&i.CanonicalUrl,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, last_status_code, consecutive_failures, dead_at, last_error, next_fetch_at, min_interval_seconds, skip_hours, skip_days, posting_interval_seconds, canonical_url, fetch_full_content
 FROM feeds
 WHERE url = $1
 `
@@ -73,19 +257,36 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.LastStatusCode,
+		&i.ConsecutiveFailures,
+		&i.DeadAt,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.MinIntervalSeconds,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.PostingIntervalSeconds,
+		&i.CanonicalUrl,
+		&i.FetchFullContent,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT name, url, user_id
+SELECT name, url, user_id, last_status_code, consecutive_failures, dead_at
 FROM feeds
 `
 
 type GetFeedsRow struct {
-	Name   string
-	Url    string
-	UserID uuid.UUID
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastStatusCode      sql.NullInt32
+	ConsecutiveFailures int32
+	DeadAt              sql.NullTime
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -97,7 +298,14 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.UserID); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastStatusCode,
+			&i.ConsecutiveFailures,
+			&i.DeadAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -111,32 +319,121 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+const getFeedSchedules = `-- name: GetFeedSchedules :many
+SELECT name, url, last_fetched_at, next_fetch_at, posting_interval_seconds, min_interval_seconds, dead_at
 FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, id
-LIMIT 1
+ORDER BY dead_at NULLS FIRST, next_fetch_at NULLS FIRST, name
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+type GetFeedSchedulesRow struct {
+	Name                   string
+	Url                    string
+	LastFetchedAt          sql.NullTime
+	NextFetchAt            sql.NullTime
+	PostingIntervalSeconds sql.NullInt32
+	MinIntervalSeconds     sql.NullInt32
+	DeadAt                 sql.NullTime
+}
+
+func (q *Queries) GetFeedSchedules(ctx context.Context) ([]GetFeedSchedulesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedSchedules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedSchedulesRow
+	for rows.Next() {
+		var i GetFeedSchedulesRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.PostingIntervalSeconds,
+			&i.MinIntervalSeconds,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeeds = `-- name: ListFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, last_status_code, consecutive_failures, dead_at, last_error, next_fetch_at, min_interval_seconds, skip_hours, skip_days, posting_interval_seconds, canonical_url, fetch_full_content
+FROM feeds
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.LastStatusCode,
+			&i.ConsecutiveFailures,
+			&i.DeadAt,
+			&i.LastError,
+			&i.NextFetchAt,
+			&i.MinIntervalSeconds,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.PostingIntervalSeconds,
+			&i.CanonicalUrl,
+			&i.FetchFullContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedDead = `-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC',
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id = $1
+`
+
+func (q *Queries) MarkFeedDead(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedDead, id)
+	return err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC', 
-    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC',
+    claimed_until = NULL
 WHERE id = $1
 `
 
@@ -144,3 +441,165 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_status_code = $2,
+    consecutive_failures = consecutive_failures + 1,
+    last_error = $3,
+    next_fetch_at = $4
+WHERE id = $1
+RETURNING consecutive_failures
+`
+
+type RecordFeedFailureParams struct {
+	ID             uuid.UUID
+	LastStatusCode sql.NullInt32
+	LastError      sql.NullString
+	NextFetchAt    sql.NullTime
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.ID,
+		arg.LastStatusCode,
+		arg.LastError,
+		arg.NextFetchAt,
+	)
+	var consecutive_failures int32
+	err := row.Scan(&consecutive_failures)
+	return consecutive_failures, err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET last_status_code = $2,
+    consecutive_failures = 0,
+    last_error = NULL,
+    next_fetch_at = $3
+WHERE id = $1
+`
+
+type RecordFeedSuccessParams struct {
+	ID             uuid.UUID
+	LastStatusCode sql.NullInt32
+	NextFetchAt    sql.NullTime
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.ID, arg.LastStatusCode, arg.NextFetchAt)
+	return err
+}
+
+const setFeedCanonicalURL = `-- name: SetFeedCanonicalURL :exec
+UPDATE feeds
+SET canonical_url = $2
+WHERE id = $1
+`
+
+type SetFeedCanonicalURLParams struct {
+	ID           uuid.UUID
+	CanonicalUrl sql.NullString
+}
+
+func (q *Queries) SetFeedCanonicalURL(ctx context.Context, arg SetFeedCanonicalURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCanonicalURL, arg.ID, arg.CanonicalUrl)
+	return err
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id = $1
+`
+
+type SetFeedFetchFullContentParams struct {
+	ID               uuid.UUID
+	FetchFullContent bool
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullContent, arg.ID, arg.FetchFullContent)
+	return err
+}
+
+const updateFeedCacheHeaders = `-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2,
+    last_modified = $3
+WHERE id = $1
+`
+
+type UpdateFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const updateFeedRefreshHints = `-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET min_interval_seconds = $2,
+    skip_hours = $3,
+    skip_days = $4
+WHERE id = $1
+`
+
+type UpdateFeedRefreshHintsParams struct {
+	ID                 uuid.UUID
+	MinIntervalSeconds sql.NullInt32
+	SkipHours          int32
+	SkipDays           int32
+}
+
+func (q *Queries) UpdateFeedRefreshHints(ctx context.Context, arg UpdateFeedRefreshHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedRefreshHints,
+		arg.ID,
+		arg.MinIntervalSeconds,
+		arg.SkipHours,
+		arg.SkipDays,
+	)
+	return err
+}
+
+const updateFeedSchedule = `-- name: UpdateFeedSchedule :exec
+UPDATE feeds
+SET posting_interval_seconds = $2,
+    next_fetch_at = $3
+WHERE id = $1
+`
+
+type UpdateFeedScheduleParams struct {
+	ID                     uuid.UUID
+	PostingIntervalSeconds sql.NullInt32
+	NextFetchAt            sql.NullTime
+}
+
+func (q *Queries) UpdateFeedSchedule(ctx context.Context, arg UpdateFeedScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedSchedule, arg.ID, arg.PostingIntervalSeconds, arg.NextFetchAt)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+    canonical_url = $3,
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID           uuid.UUID
+	Url          string
+	CanonicalUrl sql.NullString
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url, arg.CanonicalUrl)
+	return err
+}
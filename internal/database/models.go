@@ -11,14 +11,53 @@ import (
 	"github.com/google/uuid"
 )
 
+type Author struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
+}
+
+type Category struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
+}
+
+type Enclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	PostID          uuid.UUID
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+	ImageUrl        sql.NullString
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Name                   string
+	Url                    string
+	UserID                 uuid.UUID
+	LastFetchedAt          sql.NullTime
+	Etag                   sql.NullString
+	LastModified           sql.NullString
+	ClaimedUntil           sql.NullTime
+	LastStatusCode         sql.NullInt32
+	ConsecutiveFailures    int32
+	DeadAt                 sql.NullTime
+	LastError              sql.NullString
+	NextFetchAt            sql.NullTime
+	MinIntervalSeconds     sql.NullInt32
+	SkipHours              int32
+	SkipDays               int32
+	PostingIntervalSeconds sql.NullInt32
+	CanonicalUrl           sql.NullString
+	FetchFullContent       bool
 }
 
 type FeedFollow struct {
@@ -30,14 +69,30 @@ type FeedFollow struct {
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       sql.NullString
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        sql.NullString
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Guid         string
+	ContentHash  string
+	Revision     int32
+	Content      sql.NullString
+	ContentText  sql.NullString
+	CommentsUrl  sql.NullString
+	FullContent  sql.NullString
+	MetadataHash string
+	DateUnknown  bool
+}
+
+type PostRead struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.UUID
 }
 
 type User struct {
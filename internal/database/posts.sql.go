@@ -13,89 +13,166 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (
-    id,
-    created_at,
-    updated_at,
-    title,
-    url,
-    description,
-    published_at,
-    feed_id
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+const adoptBackfilledPostGUID = `-- name: AdoptBackfilledPostGUID :exec
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+AND url = $3
+AND guid = url
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = $2 AND existing.guid = $1
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       sql.NullString
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+type AdoptBackfilledPostGUIDParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+// posts stored before guids were tracked had their url copied into guid,
+// so such a row takes over the item's real guid the first time it is seen
+func (q *Queries) AdoptBackfilledPostGUID(ctx context.Context, arg AdoptBackfilledPostGUIDParams) error {
+	_, err := q.db.ExecContext(ctx, adoptBackfilledPostGUID, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
+
+const feedHasBackfilledPostGUIDs = `-- name: FeedHasBackfilledPostGUIDs :one
+SELECT EXISTS (
+    SELECT 1 FROM posts
+    WHERE feed_id = $1 AND guid = url
+)::bool AS has_backfilled_guids
+`
+
+func (q *Queries) FeedHasBackfilledPostGUIDs(ctx context.Context, feedID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, feedHasBackfilledPostGUIDs, feedID)
+	var has_backfilled_guids bool
+	err := row.Scan(&has_backfilled_guids)
+	return has_backfilled_guids, err
+}
+
+const getFollowedPostsByIDOrURL = `-- name: GetFollowedPostsByIDOrURL :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash, posts.revision, posts.content, posts.content_text, posts.comments_url, posts.full_content, posts.metadata_hash, posts.date_unknown
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND (posts.id::text = $2 OR posts.url = $2)
+`
+
+type GetFollowedPostsByIDOrURLParams struct {
+	UserID  uuid.UUID
+	IDOrUrl string
+}
+
+func (q *Queries) GetFollowedPostsByIDOrURL(ctx context.Context, arg GetFollowedPostsByIDOrURLParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedPostsByIDOrURL, arg.UserID, arg.IDOrUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Revision,
+			&i.Content,
+			&i.ContentText,
+			&i.CommentsUrl,
+			&i.FullContent,
+			&i.MetadataHash,
+			&i.DateUnknown,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
 SELECT
-posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id,
-feeds.name AS feed_name
+posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash, posts.revision, posts.content, posts.content_text, posts.comments_url, posts.full_content, posts.metadata_hash, posts.date_unknown,
+feeds.name AS feed_name,
+(post_reads.id IS NOT NULL)::bool AS is_read
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
+AND ($2::bool OR post_reads.id IS NULL)
+AND (
+    $3::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM authors
+        WHERE authors.post_id = posts.id AND lower(authors.name) = lower($3)
+    )
+)
+AND (
+    $4::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM categories
+        WHERE categories.post_id = posts.id AND lower(categories.name) = lower($4)
+    )
+)
 ORDER BY published_at DESC
-LIMIT $2
+LIMIT $5
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID      uuid.UUID
+	IncludeRead bool
+	Author      sql.NullString
+	Category    sql.NullString
+	PostLimit   int32
 }
 
 type GetPostsForUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       sql.NullString
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
-	FeedName    string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        sql.NullString
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Guid         string
+	ContentHash  string
+	Revision     int32
+	Content      sql.NullString
+	ContentText  sql.NullString
+	CommentsUrl  sql.NullString
+	FullContent  sql.NullString
+	MetadataHash string
+	DateUnknown  bool
+	FeedName     string
+	IsRead       bool
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.IncludeRead,
+		arg.Author,
+		arg.Category,
+		arg.PostLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +189,17 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Revision,
+			&i.Content,
+			&i.ContentText,
+			&i.CommentsUrl,
+			&i.FullContent,
+			&i.MetadataHash,
+			&i.DateUnknown,
 			&i.FeedName,
+			&i.IsRead,
 		); err != nil {
 			return nil, err
 		}
@@ -126,3 +213,172 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getRecentPostSpan = `-- name: GetRecentPostSpan :one
+SELECT
+COUNT(*)::int AS post_count,
+COALESCE(EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)), 0)::bigint AS span_seconds
+FROM (
+    SELECT published_at
+    FROM posts
+    WHERE feed_id = $1 AND published_at IS NOT NULL AND NOT date_unknown
+    ORDER BY published_at DESC
+    LIMIT $2
+) AS recent_posts
+`
+
+type GetRecentPostSpanParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+type GetRecentPostSpanRow struct {
+	PostCount   int32
+	SpanSeconds int64
+}
+
+func (q *Queries) GetRecentPostSpan(ctx context.Context, arg GetRecentPostSpanParams) (GetRecentPostSpanRow, error) {
+	row := q.db.QueryRowContext(ctx, getRecentPostSpan, arg.FeedID, arg.Limit)
+	var i GetRecentPostSpanRow
+	err := row.Scan(&i.PostCount, &i.SpanSeconds)
+	return i, err
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+AND guid NOT IN (
+    SELECT guid FROM posts WHERE feed_id = $1
+)
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const updatePostFullContent = `-- name: UpdatePostFullContent :exec
+UPDATE posts
+SET full_content = $2
+WHERE id = $1
+`
+
+type UpdatePostFullContentParams struct {
+	ID          uuid.UUID
+	FullContent sql.NullString
+}
+
+func (q *Queries) UpdatePostFullContent(ctx context.Context, arg UpdatePostFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostFullContent, arg.ID, arg.FullContent)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (
+    id,
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    guid,
+    content_hash,
+    content,
+    content_text,
+    comments_url,
+    metadata_hash,
+    date_unknown
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET url = EXCLUDED.url,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    content_text = EXCLUDED.content_text,
+    comments_url = EXCLUDED.comments_url,
+    published_at = CASE
+        WHEN EXCLUDED.date_unknown THEN COALESCE(posts.published_at, posts.created_at)
+        ELSE EXCLUDED.published_at
+    END,
+    date_unknown = EXCLUDED.date_unknown AND (posts.date_unknown OR posts.published_at IS NULL),
+    content_hash = EXCLUDED.content_hash,
+    metadata_hash = EXCLUDED.metadata_hash,
+    revision = CASE
+        WHEN posts.content_hash = '' OR posts.content_hash = EXCLUDED.content_hash THEN posts.revision
+        ELSE posts.revision + 1
+    END,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.url <> EXCLUDED.url
+OR posts.content_hash <> EXCLUDED.content_hash
+OR posts.metadata_hash <> EXCLUDED.metadata_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, revision, content, content_text, comments_url, full_content, metadata_hash, date_unknown
+`
+
+type UpsertPostParams struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        sql.NullString
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Guid         string
+	ContentHash  string
+	Content      sql.NullString
+	ContentText  sql.NullString
+	CommentsUrl  sql.NullString
+	MetadataHash string
+	DateUnknown  bool
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.ContentHash,
+		arg.Content,
+		arg.ContentText,
+		arg.CommentsUrl,
+		arg.MetadataHash,
+		arg.DateUnknown,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revision,
+		&i.Content,
+		&i.ContentText,
+		&i.CommentsUrl,
+		&i.FullContent,
+		&i.MetadataHash,
+		&i.DateUnknown,
+	)
+	return i, err
+}
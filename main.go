@@ -23,9 +23,18 @@ func main() {
 
 	dbQueries := database.New(db)
 
+	client, err := newHTTPClient(&cfg)
+	if err != nil {
+		fmt.Println("Error configuring http client:", err)
+		os.Exit(1)
+	}
+
 	s := &state{
-		db:     dbQueries,
-		config: &cfg,
+		db:           dbQueries,
+		sqlDB:        db,
+		config:       &cfg,
+		client:       client,
+		maxFeedBytes: maxFeedBytes(&cfg),
 	}
 
 	cmds := &commands{cmds: make(map[string]func(*state, command) error)}
@@ -37,10 +46,18 @@ func main() {
 	cmds.register("agg", HandlerAgg)
 	cmds.register("addfeed", middlewareLoggedIn(HandlerAddFeed))
 	cmds.register("feeds", HandlerFeeds)
+	cmds.register("feedstatus", HandlerFeedStatus)
+	cmds.register("schedule", HandlerSchedule)
+	cmds.register("dedupefeeds", HandlerDedupeFeeds)
+	cmds.register("fullcontent", HandlerFullContent)
 	cmds.register("follow", middlewareLoggedIn(HandlerFollow))
 	cmds.register("following", middlewareLoggedIn(HandlerFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(HandlerUnfollow))
 	cmds.register("browse", middlewareLoggedIn(HandlerBrowse))
+	cmds.register("enclosures", middlewareLoggedIn(HandlerEnclosures))
+	cmds.register("read", middlewareLoggedIn(HandlerRead))
+	cmds.register("unread", middlewareLoggedIn(HandlerUnread))
+	cmds.register("markallread", middlewareLoggedIn(HandlerMarkAllRead))
 
 	argsPassedByUser := os.Args
 	if len(argsPassedByUser) < 2 {
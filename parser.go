@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// parseFeed detects the feed format from the content type or root element
+// and converts it into the RSSFeed model used by scrapeFeeds.
+func parseFeed(data []byte, contentType string) (*RSSFeed, error) {
+	if isJSONFeed(data, contentType) {
+		return parseJSONFeed(data)
+	}
+
+	root, err := feedRootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Local {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	case "RDF":
+		return parseRDF(data)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.Local)
+	}
+}
+
+func feedRootElement(data []byte) (xml.Name, error) {
+	decoder := newXMLDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return xml.Name{}, fmt.Errorf("no root element found in feed")
+		}
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("error reading xml: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func parseRSS(data []byte) (*RSSFeed, error) {
+	v := RSSFeed{}
+	if err := newXMLDecoder(data).Decode(&v); err != nil {
+		return nil, fmt.Errorf("error unmarshaling xml: %w", err)
+	}
+	v.Channel.Link = unnamespacedValue(v.Channel.LinkElements)
+	for i := range v.Channel.Item {
+		item := &v.Channel.Item[i]
+		item.Link = unnamespacedValue(item.LinkElements)
+		item.Comments = unnamespacedValue(item.CommentsElements)
+		mergeMediaEnclosures(item)
+	}
+	return &v, nil
+}
+
+// mergeMediaEnclosures folds media:content elements into the item's
+// enclosures, skipping urls that are already attached, and applies the
+// itunes duration and image to them.
+func mergeMediaEnclosures(item *RSSItem) {
+	for _, media := range append(item.MediaContent, item.MediaGroup...) {
+		if media.URL == "" {
+			continue
+		}
+		mediaType := media.Type
+		if mediaType == "" && media.Medium == "image" {
+			// thumbnails and artwork aren't downloadable media
+			continue
+		}
+
+		i := slices.IndexFunc(item.Enclosures, func(e RSSEnclosure) bool { return e.URL == media.URL })
+		if i < 0 {
+			item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: media.URL, Type: mediaType, Length: media.FileSize})
+			i = len(item.Enclosures) - 1
+		}
+		enclosure := &item.Enclosures[i]
+		if enclosure.Type == "" {
+			enclosure.Type = mediaType
+		}
+		if enclosure.Length == "" {
+			enclosure.Length = media.FileSize
+		}
+		if enclosure.Duration == "" {
+			enclosure.Duration = media.Duration
+		}
+	}
+
+	for i := range item.Enclosures {
+		if item.Enclosures[i].Duration == "" {
+			item.Enclosures[i].Duration = item.ItunesDuration
+		}
+		item.Enclosures[i].Image = item.ItunesImage.Href
+	}
+}
+
+func parseRDF(data []byte) (*RSSFeed, error) {
+	rdf := RDFFeed{}
+	if err := newXMLDecoder(data).Decode(&rdf); err != nil {
+		return nil, fmt.Errorf("error unmarshaling rdf: %w", err)
+	}
+
+	v := RSSFeed{}
+	v.Channel.Title = rdf.Channel.Title
+	v.Channel.Link = rdf.Channel.Link
+	v.Channel.Description = rdf.Channel.Description
+	v.Channel.UpdatePeriod = rdf.Channel.UpdatePeriod
+	v.Channel.UpdateFrequency = rdf.Channel.UpdateFrequency
+
+	for _, entry := range rdf.Item {
+		v.Channel.Item = append(v.Channel.Item, RSSItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Description,
+			Content:     entry.Content,
+			PubDate:     entry.Date,
+			Author:      entry.Creator,
+			Categories:  entry.Subjects,
+			GUID:        entry.About,
+		})
+	}
+
+	return &v, nil
+}
+
+func parseAtom(data []byte) (*RSSFeed, error) {
+	atom := AtomFeed{}
+	if err := newXMLDecoder(data).Decode(&atom); err != nil {
+		return nil, fmt.Errorf("error unmarshaling atom: %w", err)
+	}
+
+	v := RSSFeed{}
+	v.Channel.Title = atom.Title.String()
+	v.Channel.Link = atomAlternateLink(atom.Link)
+	v.Channel.Description = atom.Subtitle.String()
+	v.Channel.Base = atom.Base
+
+	for _, entry := range atom.Entry {
+		item := RSSItem{
+			Title:   entry.Title.String(),
+			Link:    atomAlternateLink(entry.Link),
+			PubDate: entry.Published,
+			GUID:    strings.TrimSpace(entry.ID),
+			Base:    entry.Base,
+		}
+		for _, link := range entry.Link {
+			switch {
+			case link.Href == "":
+			case link.Rel == "enclosure":
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: link.Href, Type: link.Type, Length: link.Length})
+			case link.Rel == "replies" && (item.Comments == "" || link.Type == "text/html"):
+				item.Comments = link.Href
+			}
+		}
+
+		// entries without an author inherit the feed's
+		authors := entry.Author
+		if len(authors) == 0 {
+			authors = atom.Author
+		}
+		for _, author := range authors {
+			name := strings.TrimSpace(author.Name)
+			if name == "" {
+				name = strings.TrimSpace(author.Email)
+			}
+			item.Creators = append(item.Creators, name)
+		}
+		for _, category := range entry.Category {
+			if category.Label != "" {
+				item.Categories = append(item.Categories, category.Label)
+			} else {
+				item.Categories = append(item.Categories, category.Term)
+			}
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.Updated
+		}
+
+		item.Content = entry.Content.HTML()
+		item.Description = entry.Summary.String()
+		if item.Description == "" {
+			item.Description = item.Content
+		}
+
+		v.Channel.Item = append(v.Channel.Item, item)
+	}
+
+	return &v, nil
+}
+
+// atomAlternateLink returns the rel="alternate" link, which is also the
+// default when rel is omitted. Falls back to the first link with an href.
+func atomAlternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if (link.Rel == "" || link.Rel == "alternate") && link.Href != "" {
+			return link.Href
+		}
+	}
+	for _, link := range links {
+		if link.Href != "" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// isJSONFeed trusts a json content type unless the body is clearly markup,
+// since some servers label every feed they serve the same way.
+func isJSONFeed(data []byte, contentType string) bool {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(contentType), "json") || bytes.HasPrefix(trimmed, []byte("{"))
+}
+
+func parseJSONFeed(data []byte) (*RSSFeed, error) {
+	feed := JSONFeed{}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("error unmarshaling json feed: %w", err)
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("unsupported json feed version: %q", feed.Version)
+	}
+
+	v := RSSFeed{}
+	v.Channel.Title = feed.Title
+	v.Channel.Link = feed.HomePageURL
+	v.Channel.Description = feed.Description
+
+	for _, entry := range feed.Items {
+		item := RSSItem{
+			Title:   entry.Title,
+			Link:    entry.URL,
+			PubDate: entry.DatePublished,
+			GUID:    entry.IDString(),
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.DateModified
+		}
+
+		switch {
+		case entry.ContentHTML != "":
+			item.Description = entry.ContentHTML
+		case entry.ContentText != "":
+			item.Description = entry.ContentText
+		default:
+			item.Description = entry.Summary
+		}
+		if entry.ContentHTML != "" {
+			item.Content = entry.ContentHTML
+		} else {
+			item.Content = html.EscapeString(entry.ContentText)
+		}
+
+		authors := entry.Authors
+		if len(authors) == 0 && entry.Author != nil {
+			authors = []JSONFeedAuthor{*entry.Author}
+		}
+		for _, author := range authors {
+			item.Creators = append(item.Creators, author.Name)
+		}
+		item.Categories = entry.Tags
+
+		for _, attachment := range entry.Attachments {
+			enclosure := RSSEnclosure{URL: attachment.URL, Type: attachment.MimeType}
+			if attachment.SizeInBytes > 0 {
+				enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+			}
+			if attachment.DurationInSeconds > 0 {
+				enclosure.Duration = strconv.Itoa(int(attachment.DurationInSeconds))
+			}
+			item.Enclosures = append(item.Enclosures, enclosure)
+		}
+
+		v.Channel.Item = append(v.Channel.Item, item)
+	}
+
+	return &v, nil
+}
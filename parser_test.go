@@ -0,0 +1,103 @@
+package main
+
+import "testing"
+
+const wordPressFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:slash="http://purl.org/rss/1.0/modules/slash/"
+	xmlns:wfw="http://wellformedweb.org/CommentAPI/">
+<channel>
+	<title>Example Blog</title>
+	<atom:link href="https://blog.example.com/feed/" rel="self" type="application/rss+xml" />
+	<link>https://blog.example.com</link>
+	<description>Just another blog</description>
+	<item>
+		<title>Hello world</title>
+		<link>https://blog.example.com/hello-world/</link>
+		<comments>https://blog.example.com/hello-world/#respond</comments>
+		<dc:creator><![CDATA[admin]]></dc:creator>
+		<pubDate>Tue, 05 Mar 2024 10:00:00 +0000</pubDate>
+		<category><![CDATA[News]]></category>
+		<guid isPermaLink="false">https://blog.example.com/?p=1</guid>
+		<description><![CDATA[Welcome to the blog.]]></description>
+		<wfw:commentRss>https://blog.example.com/hello-world/feed/</wfw:commentRss>
+		<slash:comments>0</slash:comments>
+	</item>
+	<item>
+		<title>No comments link</title>
+		<link>https://blog.example.com/second/</link>
+		<guid isPermaLink="false">https://blog.example.com/?p=2</guid>
+		<slash:comments>3</slash:comments>
+	</item>
+</channel>
+</rss>`
+
+func TestParseRSSComments(t *testing.T) {
+	feed, err := parseFeed([]byte(wordPressFeed), "application/rss+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	want := []string{"https://blog.example.com/hello-world/#respond", ""}
+	for i, item := range feed.Channel.Item {
+		if item.Comments != want[i] {
+			t.Errorf("item %d comments = %q, want %q", i, item.Comments, want[i])
+		}
+	}
+}
+
+// hugoFeed follows Hugo's default RSS template, where atom:link comes after
+// the channel link.
+const hugoFeed = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Example Site</title>
+    <link>https://site.example.com/blog/</link>
+    <description>Recent content</description>
+    <atom:link href="https://site.example.com/index.xml" rel="self" type="application/rss+xml" />
+    <item>
+      <title>First post</title>
+      <link>posts/first/</link>
+      <atom:link href="https://elsewhere.example.com/first" rel="alternate" />
+      <guid>posts/first/</guid>
+    </item>
+  </channel>
+</rss>`
+
+func TestParseRSSChannelLink(t *testing.T) {
+	feed, err := parseFeed([]byte(hugoFeed), "application/rss+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	if feed.Channel.Link != "https://site.example.com/blog/" {
+		t.Errorf("channel link = %q, want %q", feed.Channel.Link, "https://site.example.com/blog/")
+	}
+
+	resolveItemURLs(feed, "https://cdn.example.com/index.xml")
+	item := feed.Channel.Item[0]
+	if want := "https://site.example.com/blog/posts/first/"; item.Link != want {
+		t.Errorf("item link = %q, want %q", item.Link, want)
+	}
+}
+
+func TestResolveItemURLsChannelBase(t *testing.T) {
+	feed := &RSSFeed{}
+	feed.Channel.Link = "about/"
+	feed.Channel.Base = "/blog/"
+	feed.Channel.Item = []RSSItem{{Link: "posts/first/"}}
+
+	resolveItemURLs(feed, "https://site.example.com/feeds/index.xml")
+
+	if want := "https://site.example.com/blog/about/"; feed.Channel.Link != want {
+		t.Errorf("channel link = %q, want %q", feed.Channel.Link, want)
+	}
+	if want := "https://site.example.com/blog/posts/first/"; feed.Channel.Item[0].Link != want {
+		t.Errorf("item link = %q, want %q", feed.Channel.Item[0].Link, want)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IlMeloIl/RSS/internal/database"
+	"github.com/google/uuid"
+)
+
+// itemAuthors collects the item's author names from <author> and
+// dc:creator. RSS authors are usually "email (Name)", so the name in
+// parentheses is preferred over the address.
+func itemAuthors(item RSSItem) []string {
+	author := strings.TrimSpace(item.Author)
+	if open := strings.LastIndex(author, "("); open > 0 && strings.HasSuffix(author, ")") {
+		if name := strings.TrimSpace(author[open+1 : len(author)-1]); name != "" {
+			author = name
+		}
+	}
+	return uniqueNames(append([]string{author}, item.Creators...))
+}
+
+func itemCategories(item RSSItem) []string {
+	return uniqueNames(item.Categories)
+}
+
+// uniqueNames trims the names and drops empty ones and case-insensitive
+// duplicates, keeping the first spelling seen.
+func uniqueNames(names []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, name)
+	}
+	return unique
+}
+
+// savePostMetadata replaces the stored authors and categories of a post with
+// the ones the feed currently lists for it.
+func savePostMetadata(ctx context.Context, s *state, postID uuid.UUID, item RSSItem) error {
+	if err := s.db.DeleteAuthorsForPost(ctx, postID); err != nil {
+		return fmt.Errorf("error deleting old authors: %w", err)
+	}
+	for _, name := range itemAuthors(item) {
+		if err := s.db.CreateAuthor(ctx, database.CreateAuthorParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			PostID:    postID,
+			Name:      name,
+		}); err != nil {
+			return fmt.Errorf("error saving author %s: %w", name, err)
+		}
+	}
+
+	if err := s.db.DeleteCategoriesForPost(ctx, postID); err != nil {
+		return fmt.Errorf("error deleting old categories: %w", err)
+	}
+	for _, name := range itemCategories(item) {
+		if err := s.db.CreateCategory(ctx, database.CreateCategoryParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			PostID:    postID,
+			Name:      name,
+		}); err != nil {
+			return fmt.Errorf("error saving category %s: %w", name, err)
+		}
+	}
+	return nil
+}
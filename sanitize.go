@@ -0,0 +1,205 @@
+package main
+
+import (
+	"html"
+	"slices"
+	"strings"
+)
+
+// droppedElements are removed from post content together with everything
+// inside them.
+var droppedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"frame":    true,
+	"frameset": true,
+	"object":   true,
+	"embed":    true,
+	"applet":   true,
+	"form":     true,
+	"textarea": true,
+	"select":   true,
+	"noscript": true,
+	"template": true,
+	"title":    true,
+	"svg":      true,
+	"math":     true,
+}
+
+// allowedTags lists the tags kept by sanitizeHTML and the attributes each
+// may carry besides globalAttrs. Other tags are removed but their text kept.
+var allowedTags = map[string][]string{
+	"a":          {"href"},
+	"abbr":       nil,
+	"audio":      {"src", "controls"},
+	"b":          nil,
+	"blockquote": {"cite"},
+	"br":         nil,
+	"caption":    nil,
+	"cite":       nil,
+	"code":       nil,
+	"dd":         nil,
+	"del":        {"datetime"},
+	"details":    nil,
+	"dfn":        nil,
+	"div":        nil,
+	"dl":         nil,
+	"dt":         nil,
+	"em":         nil,
+	"figcaption": nil,
+	"figure":     nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"img":        {"src", "alt", "width", "height"},
+	"ins":        {"datetime"},
+	"kbd":        nil,
+	"li":         nil,
+	"mark":       nil,
+	"ol":         {"start", "reversed"},
+	"p":          nil,
+	"picture":    nil,
+	"pre":        nil,
+	"q":          {"cite"},
+	"s":          nil,
+	"samp":       nil,
+	"small":      nil,
+	"source":     {"src", "type"},
+	"span":       nil,
+	"strike":     nil,
+	"strong":     nil,
+	"sub":        nil,
+	"summary":    nil,
+	"sup":        nil,
+	"table":      nil,
+	"tbody":      nil,
+	"td":         {"colspan", "rowspan"},
+	"tfoot":      nil,
+	"th":         {"colspan", "rowspan", "scope"},
+	"thead":      nil,
+	"time":       {"datetime"},
+	"tr":         nil,
+	"u":          nil,
+	"ul":         nil,
+	"video":      {"src", "controls", "poster", "width", "height"},
+}
+
+var globalAttrs = []string{"title", "lang", "dir"}
+
+// urlAttrs hold urls that are checked for a safe scheme.
+var urlAttrs = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"cite":   true,
+	"poster": true,
+}
+
+// voidElements have no content and no end tag.
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// sanitizeHTML rebuilds a feed's HTML keeping only allowlisted tags and
+// attributes, so it can be stored and shown later without running scripts,
+// loading embedded documents or following javascript: urls.
+func sanitizeHTML(fragment string) string {
+	out := strings.Builder{}
+	skipping := ""
+	depth := 0
+
+	for _, token := range tokenizeHTML(fragment) {
+		if skipping != "" {
+			switch {
+			case token.Type == htmlStartTag && token.Data == skipping:
+				depth++
+			case token.Type == htmlEndTag && token.Data == skipping:
+				depth--
+				if depth == 0 {
+					skipping = ""
+				}
+			}
+			continue
+		}
+
+		switch token.Type {
+		case htmlText:
+			out.WriteString(html.EscapeString(token.Data))
+		case htmlStartTag, htmlSelfClosingTag:
+			if droppedElements[token.Data] {
+				if token.Type == htmlStartTag && !voidElements[token.Data] {
+					skipping, depth = token.Data, 1
+				}
+				continue
+			}
+			attrs, ok := allowedTags[token.Data]
+			if !ok {
+				continue
+			}
+			out.WriteString("<" + token.Data)
+			for _, attr := range token.Attrs {
+				if !allowedAttr(attr, attrs) {
+					continue
+				}
+				out.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			out.WriteString(">")
+		case htmlEndTag:
+			if _, ok := allowedTags[token.Data]; ok && !voidElements[token.Data] {
+				out.WriteString("</" + token.Data + ">")
+			}
+		}
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+func allowedAttr(attr htmlAttr, tagAttrs []string) bool {
+	if !slices.Contains(tagAttrs, attr.Key) && !slices.Contains(globalAttrs, attr.Key) {
+		return false
+	}
+	if urlAttrs[attr.Key] {
+		return safeURL(attr.Val)
+	}
+	return true
+}
+
+// safeURL reports whether a link or source url uses a scheme that can't run
+// code. Relative urls have no scheme and are allowed.
+func safeURL(rawURL string) bool {
+	// browsers ignore whitespace and control characters inside the scheme
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, rawURL)
+
+	scheme, _, ok := strings.Cut(cleaned, ":")
+	if !ok || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
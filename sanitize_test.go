@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"allowed markup", `<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{"script dropped with its content", `<p>a</p><script>alert("x")</script><p>b</p>`, `<p>a</p><p>b</p>`},
+		{"mixed case script", `<SCRIPT type="text/javascript">alert(1)</ScRiPt>text`, `text`},
+		{"nested dropped elements", `<object><object>x</object>y</object>z`, `z`},
+		{"style and iframe dropped", `<style>p{}</style><iframe src="https://e.com"></iframe>ok`, `ok`},
+		{"unknown tag keeps its text", `<custom-box><p>inside</p></custom-box>`, `<p>inside</p>`},
+		{"event handlers removed", `<img src="a.png" onerror="alert(1)" alt="pic">`, `<img src="a.png" alt="pic">`},
+		{"onclick on link removed", `<a href="https://e.com" onclick="x()">go</a>`, `<a href="https://e.com">go</a>`},
+		{"style attribute removed", `<p style="color:red" class="c" title="t">x</p>`, `<p title="t">x</p>`},
+		{"javascript url removed", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript url with case and whitespace", `<a href=" JaVa&#x09;Script:alert(1)">x</a>`, `<a>x</a>`},
+		{"data url removed", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, `<img>`},
+		{"vbscript url removed", `<a href="vbscript:msgbox">x</a>`, `<a>x</a>`},
+		{"relative and mailto urls kept", `<a href="/post?a=1&amp;b=2">x</a> <a href="mailto:me@e.com">m</a>`, `<a href="/post?a=1&amp;b=2">x</a> <a href="mailto:me@e.com">m</a>`},
+		{"attribute values escaped", `<img alt='"><script>x</script>'>`, `<img alt="&#34;&gt;&lt;script&gt;x&lt;/script&gt;">`},
+		{"entities decoded and escaped again", `caf&eacute; &amp; &lt;tag&gt; &#8212;`, `café &amp; &lt;tag&gt; —`},
+		{"bare less than", `a < b and c<d`, `a &lt; b and c&lt;d`},
+		{"comments removed", `a<!-- <script>x</script> -->b`, `ab`},
+		{"void element end tag dropped", `<br></br>x`, `<br>x`},
+		{"unterminated tag is text", `x <p class="a`, `x &lt;p class=&#34;a`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.input); got != tt.want {
+				t.Errorf("sanitizeHTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/a", true},
+		{"HTTP://example.com", true},
+		{"mailto:me@example.com", true},
+		{"/relative/path", true},
+		{"page?x=a:b", true},
+		{"#anchor", true},
+		{"javascript:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"java\nscript:alert(1)", false},
+		{"JAVASCRIPT:alert(1)", false},
+		{"data:text/html,<script>x</script>", false},
+		{"vbscript:x", false},
+		{"file:///etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		if got := safeURL(tt.url); got != tt.want {
+			t.Errorf("safeURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
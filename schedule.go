@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IlMeloIl/RSS/internal/database"
+	"github.com/google/uuid"
+)
+
+// feedRefreshHints are the limits a publisher declares on how often its feed
+// should be fetched.
+type feedRefreshHints struct {
+	MinInterval time.Duration
+	SkipHours   int32 // bit n skips hour n (UTC)
+	SkipDays    int32 // bit n skips time.Weekday(n)
+}
+
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// refreshHintsFromFeed reads <ttl>, <skipHours>, <skipDays> and the
+// syndication module's updatePeriod/updateFrequency. When both ttl and the
+// syndication module are present, the longer interval wins.
+func refreshHintsFromFeed(v *RSSFeed) feedRefreshHints {
+	hints := feedRefreshHints{}
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(v.Channel.TTL)); err == nil && ttl > 0 {
+		hints.MinInterval = time.Duration(ttl) * time.Minute
+	}
+
+	if period, ok := syndicationPeriods[strings.ToLower(strings.TrimSpace(v.Channel.UpdatePeriod))]; ok {
+		frequency, err := strconv.Atoi(strings.TrimSpace(v.Channel.UpdateFrequency))
+		if err != nil || frequency < 1 {
+			frequency = 1
+		}
+		hints.MinInterval = max(hints.MinInterval, period/time.Duration(frequency))
+	}
+
+	for _, hour := range v.Channel.SkipHours {
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && h >= 0 && h < 24 {
+			hints.SkipHours |= 1 << h
+		}
+	}
+
+	for _, day := range v.Channel.SkipDays {
+		if d, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]; ok {
+			hints.SkipDays |= 1 << d
+		}
+	}
+
+	return hints
+}
+
+func refreshHintsFromDB(feed database.Feed) feedRefreshHints {
+	return feedRefreshHints{
+		MinInterval: time.Duration(feed.MinIntervalSeconds.Int32) * time.Second,
+		SkipHours:   feed.SkipHours,
+		SkipDays:    feed.SkipDays,
+	}
+}
+
+func (h feedRefreshHints) minIntervalSeconds() sql.NullInt32 {
+	if h.MinInterval <= 0 {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(h.MinInterval / time.Second), Valid: true}
+}
+
+// nextFetch returns when the feed is next due after a fetch at now, or an
+// invalid time when the publisher declared no limits.
+func (h feedRefreshHints) nextFetch(now time.Time) sql.NullTime {
+	if h.MinInterval <= 0 && h.SkipHours == 0 && h.SkipDays == 0 {
+		return sql.NullTime{}
+	}
+
+	next := now.UTC().Add(h.MinInterval)
+
+	// step to the start of the next hour until neither the hour nor the day
+	// is skipped; a week is enough to see every combination
+	for range 7 * 24 {
+		if h.SkipHours&(1<<next.Hour()) == 0 && h.SkipDays&(1<<next.Weekday()) == 0 {
+			break
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return sql.NullTime{Time: next, Valid: true}
+}
+
+// pollingLimits bound the polling interval learned from a feed's posting
+// history.
+type pollingLimits struct {
+	Floor   time.Duration
+	Ceiling time.Duration
+}
+
+// postingHistorySize is how many of the newest posts are used to learn a
+// feed's cadence.
+const postingHistorySize = 20
+
+// postingInterval returns the average time between the feed's recent posts,
+// or zero when there are too few dated posts to tell.
+func postingInterval(ctx context.Context, s *state, feedID uuid.UUID) (time.Duration, error) {
+	span, err := s.db.GetRecentPostSpan(ctx, database.GetRecentPostSpanParams{
+		FeedID: feedID,
+		Limit:  postingHistorySize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting posting history: %w", err)
+	}
+	if span.PostCount < 2 || span.SpanSeconds <= 0 {
+		return 0, nil
+	}
+	return time.Duration(span.SpanSeconds) * time.Second / time.Duration(span.PostCount-1), nil
+}
+
+// scheduleFeed learns how often the feed publishes and sets when it is next
+// due. Feeds are polled at twice their posting rate, kept within limits, and
+// never sooner than the publisher's own hints allow.
+func scheduleFeed(ctx context.Context, s *state, feed database.Feed, hints feedRefreshHints, limits pollingLimits) error {
+	interval, err := postingInterval(ctx, s, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	postingSeconds := sql.NullInt32{}
+	if interval > 0 {
+		postingSeconds = sql.NullInt32{Int32: int32(interval / time.Second), Valid: true}
+		hints.MinInterval = max(hints.MinInterval, min(max(interval/2, limits.Floor), limits.Ceiling))
+	}
+
+	if err = s.db.UpdateFeedSchedule(ctx, database.UpdateFeedScheduleParams{
+		ID:                     feed.ID,
+		PostingIntervalSeconds: postingSeconds,
+		NextFetchAt:            hints.nextFetch(time.Now()),
+	}); err != nil {
+		return fmt.Errorf("error updating feed schedule: %w", err)
+	}
+	return nil
+}
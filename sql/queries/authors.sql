@@ -0,0 +1,17 @@
+-- name: CreateAuthor :exec
+INSERT INTO authors (id, created_at, post_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (post_id, name) DO NOTHING;
+
+-- name: DeleteAuthorsForPost :exec
+DELETE FROM authors WHERE post_id = $1;
+
+-- name: GetAuthorsForPost :many
+SELECT name FROM authors
+WHERE post_id = $1
+ORDER BY created_at, name;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- name: CreateCategory :exec
+INSERT INTO categories (id, created_at, post_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (post_id, name) DO NOTHING;
+
+-- name: DeleteCategoriesForPost :exec
+DELETE FROM categories WHERE post_id = $1;
+
+-- name: GetCategoriesForPost :many
+SELECT name FROM categories
+WHERE post_id = $1
+ORDER BY created_at, name;
\ No newline at end of file
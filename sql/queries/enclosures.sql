@@ -0,0 +1,34 @@
+-- name: CreateEnclosure :exec
+INSERT INTO enclosures (id, created_at, post_id, url, mime_type, length, duration_seconds, image_url)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+);
+
+-- name: DeleteEnclosuresForPost :exec
+DELETE FROM enclosures WHERE post_id = $1;
+
+-- name: GetEnclosuresForPost :many
+SELECT * FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at;
+
+-- name: GetEnclosuresForUser :many
+SELECT
+enclosures.*,
+posts.title AS post_title,
+posts.published_at,
+feeds.name AS feed_name
+FROM enclosures
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC NULLS LAST, enclosures.created_at
+LIMIT $2;
\ No newline at end of file
@@ -24,7 +24,15 @@ ON feeds.id = inserted_feed_follow.feed_id;
 SELECT 
 feed_follows.*,
 feeds.name AS feed_name,
-users.name AS user_name
+users.name AS user_name,
+(
+    SELECT COUNT(*) FROM posts
+    WHERE posts.feed_id = feed_follows.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+    )
+)::int AS unread_count
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 INNER JOIN users ON users.id = feed_follows.user_id
@@ -34,4 +42,12 @@ WHERE feed_follows.user_id = $1;
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1 AND feed_id = (
     SELECT id FROM feeds WHERE url = $2
+);
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows WHERE feed_id = sqlc.arg(to_feed_id)
 );
\ No newline at end of file
@@ -1,17 +1,18 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, canonical_url)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
 RETURNING *;
 
 -- name: GetFeeds :many
-SELECT name, url, user_id
+SELECT name, url, user_id, last_status_code, consecutive_failures, dead_at
 FROM feeds;
 
 -- name: GetFeedByURL :one
@@ -22,12 +23,108 @@ WHERE url = $1;
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC', 
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC',
+    claimed_until = NULL
+WHERE id = $1;
+
+-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2,
+    last_modified = $3
+WHERE id = $1;
+
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = $3,
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE (last_fetched_at IS NULL OR last_fetched_at < $1)
+    AND (claimed_until IS NULL OR claimed_until < CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+    AND dead_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+    ORDER BY last_fetched_at NULLS FIRST, id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2,
+    canonical_url = $3,
     updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
 WHERE id = $1;
 
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET last_status_code = $2,
+    consecutive_failures = 0,
+    last_error = NULL,
+    next_fetch_at = $3
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_status_code = $2,
+    consecutive_failures = consecutive_failures + 1,
+    last_error = $3,
+    next_fetch_at = $4
+WHERE id = $1
+RETURNING consecutive_failures;
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC',
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: GetFailingFeeds :many
 SELECT *
 FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, id
-LIMIT 1;
\ No newline at end of file
+WHERE consecutive_failures > 0
+ORDER BY dead_at NULLS FIRST, consecutive_failures DESC, name;
+
+-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET min_interval_seconds = $2,
+    skip_hours = $3,
+    skip_days = $4
+WHERE id = $1;
+
+-- name: UpdateFeedSchedule :exec
+UPDATE feeds
+SET posting_interval_seconds = $2,
+    next_fetch_at = $3
+WHERE id = $1;
+
+-- name: GetFeedSchedules :many
+SELECT name, url, last_fetched_at, next_fetch_at, posting_interval_seconds, min_interval_seconds, dead_at
+FROM feeds
+ORDER BY dead_at NULLS FIRST, next_fetch_at NULLS FIRST, name;
+
+-- name: GetFeedByCanonicalURL :one
+SELECT *
+FROM feeds
+WHERE canonical_url = $1;
+
+-- name: ListFeeds :many
+SELECT *
+FROM feeds
+ORDER BY created_at, id;
+
+-- name: SetFeedCanonicalURL :exec
+UPDATE feeds
+SET canonical_url = $2
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = CURRENT_TIMESTAMP AT TIME ZONE 'UTC'
+WHERE id = $1;
\ No newline at end of file
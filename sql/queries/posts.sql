@@ -1,4 +1,4 @@
--- name: CreatePost :one
+-- name: UpsertPost :one
 INSERT INTO posts (
     id,
     created_at,
@@ -7,19 +7,116 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    guid,
+    content_hash,
+    content,
+    content_text,
+    comments_url,
+    metadata_hash,
+    date_unknown
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
 )
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET url = EXCLUDED.url,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    content_text = EXCLUDED.content_text,
+    comments_url = EXCLUDED.comments_url,
+    published_at = CASE
+        WHEN EXCLUDED.date_unknown THEN COALESCE(posts.published_at, posts.created_at)
+        ELSE EXCLUDED.published_at
+    END,
+    date_unknown = EXCLUDED.date_unknown AND (posts.date_unknown OR posts.published_at IS NULL),
+    content_hash = EXCLUDED.content_hash,
+    metadata_hash = EXCLUDED.metadata_hash,
+    revision = CASE
+        WHEN posts.content_hash = '' OR posts.content_hash = EXCLUDED.content_hash THEN posts.revision
+        ELSE posts.revision + 1
+    END,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.url <> EXCLUDED.url
+OR posts.content_hash <> EXCLUDED.content_hash
+OR posts.metadata_hash <> EXCLUDED.metadata_hash
 RETURNING *;
 
+-- name: AdoptBackfilledPostGUID :exec
+-- posts stored before guids were tracked had their url copied into guid,
+-- so such a row takes over the item's real guid the first time it is seen
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE feed_id = sqlc.arg(feed_id)
+AND url = sqlc.arg(url)
+AND guid = url
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = sqlc.arg(feed_id) AND existing.guid = sqlc.arg(guid)
+);
+
+-- name: FeedHasBackfilledPostGUIDs :one
+SELECT EXISTS (
+    SELECT 1 FROM posts
+    WHERE feed_id = $1 AND guid = url
+)::bool AS has_backfilled_guids;
+
+-- name: GetFollowedPostsByIDOrURL :many
+SELECT posts.*
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (posts.id::text = sqlc.arg(id_or_url) OR posts.url = sqlc.arg(id_or_url));
+
 -- name: GetPostsForUser :many
 SELECT
 posts.*,
-feeds.name AS feed_name
+feeds.name AS feed_name,
+(post_reads.id IS NOT NULL)::bool AS is_read
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.arg(include_read)::bool OR post_reads.id IS NULL)
+AND (
+    sqlc.narg(author)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM authors
+        WHERE authors.post_id = posts.id AND lower(authors.name) = lower(sqlc.narg(author))
+    )
+)
+AND (
+    sqlc.narg(category)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM categories
+        WHERE categories.post_id = posts.id AND lower(categories.name) = lower(sqlc.narg(category))
+    )
+)
 ORDER BY published_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT sqlc.arg(post_limit);
+
+-- name: GetRecentPostSpan :one
+SELECT
+COUNT(*)::int AS post_count,
+COALESCE(EXTRACT(EPOCH FROM MAX(published_at) - MIN(published_at)), 0)::bigint AS span_seconds
+FROM (
+    SELECT published_at
+    FROM posts
+    WHERE feed_id = $1 AND published_at IS NOT NULL AND NOT date_unknown
+    ORDER BY published_at DESC
+    LIMIT $2
+) AS recent_posts;
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND guid NOT IN (
+    SELECT guid FROM posts WHERE feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: UpdatePostFullContent :exec
+UPDATE posts
+SET full_content = $2
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content_hash TEXT NOT NULL DEFAULT '',
+ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content_hash,
+DROP COLUMN revision;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- +goose Up
+-- skip_hours and skip_days are bitmasks: bit n skips hour n (UTC) or
+-- weekday n, counting from Sunday.
+ALTER TABLE feeds
+ADD COLUMN min_interval_seconds INTEGER,
+ADD COLUMN skip_hours INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN skip_days INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN min_interval_seconds,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days;
\ No newline at end of file
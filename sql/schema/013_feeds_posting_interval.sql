@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN posting_interval_seconds INTEGER;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN posting_interval_seconds;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +goose Up
+-- canonical_url identifies a feed regardless of scheme, host case, trailing
+-- slash and tracking parameters. It is filled for existing feeds by the
+-- dedupefeeds command.
+ALTER TABLE feeds
+ADD COLUMN canonical_url TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN canonical_url;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN comments_url TEXT;
+
+CREATE TABLE authors(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL references posts(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (post_id, name)
+);
+
+CREATE TABLE categories(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL references posts(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (post_id, name)
+);
+
+-- +goose Down
+DROP TABLE categories;
+DROP TABLE authors;
+
+ALTER TABLE posts
+DROP COLUMN comments_url;
\ No newline at end of file
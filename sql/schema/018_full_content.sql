@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN fetch_full_content BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE posts
+ADD COLUMN full_content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN full_content;
+
+ALTER TABLE feeds
+DROP COLUMN fetch_full_content;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE post_reads(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL references users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL references posts(id) ON DELETE CASCADE,
+    UNIQUE (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_reads;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN date_unknown BOOLEAN NOT NULL DEFAULT false;
+
+-- posts without a usable date were dated when first seen, which made their
+-- published_at equal to created_at
+UPDATE posts SET date_unknown = true WHERE published_at = created_at;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN date_unknown;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- +goose Up
+-- finds posts whose guid is still the url copied in by 006_posts_guid
+CREATE INDEX posts_backfilled_guid_idx ON posts (feed_id, url) WHERE guid = url;
+
+-- +goose Down
+DROP INDEX posts_backfilled_guid_idx;
\ No newline at end of file
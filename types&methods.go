@@ -1,15 +1,25 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"html"
+	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/IlMeloIl/RSS/internal/config"
 	"github.com/IlMeloIl/RSS/internal/database"
 )
 
 type state struct {
-	db     *database.Queries
-	config *config.Config
+	db           *database.Queries
+	sqlDB        *sql.DB
+	config       *config.Config
+	client       *http.Client
+	maxFeedBytes int64
 }
 
 type command struct {
@@ -17,6 +27,41 @@ type command struct {
 	args []string
 }
 
+// parseFlags splits the args into positional arguments and "--name value"
+// flags. Flags listed in boolFlags take no value and are set to "true".
+func (c command) parseFlags(boolFlags ...string) ([]string, map[string]string, error) {
+	positional := []string{}
+	flags := map[string]string{}
+
+	for i := 0; i < len(c.args); i++ {
+		arg := c.args[i]
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name := strings.TrimPrefix(arg, "--")
+		if name == "" || strings.HasPrefix(name, "=") {
+			return nil, nil, fmt.Errorf("invalid flag: %s", arg)
+		}
+		if before, after, ok := strings.Cut(name, "="); ok {
+			flags[before] = after
+			continue
+		}
+		if slices.Contains(boolFlags, name) {
+			flags[name] = "true"
+			continue
+		}
+		if i+1 >= len(c.args) {
+			return nil, nil, fmt.Errorf("flag --%s needs a value", name)
+		}
+		flags[name] = c.args[i+1]
+		i++
+	}
+
+	return positional, flags, nil
+}
+
 type commands struct {
 	cmds map[string]func(*state, command) error
 }
@@ -32,18 +77,242 @@ func (c *commands) run(s *state, cmd command) error {
 	return fmt.Errorf("unknown command: %s", cmd.name)
 }
 
+// feedValidators are the cache headers of a previous response, sent back so
+// an unchanged feed can answer 304 Not Modified.
+type feedValidators struct {
+	ETag         string
+	LastModified string
+}
+
+type fetchResult struct {
+	Feed         *RSSFeed // nil when NotModified is set
+	NotModified  bool
+	Validators   feedValidators
+	StatusCode   int
+	PermanentURL string // set when the feed answered with a permanent redirect
+	FinalURL     string // url the body was served from, after redirects
+}
+
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected http status: %s", e.Status)
+}
+
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title           string    `xml:"title"`
+		Link            string    `xml:"-"`
+		Description     string    `xml:"description"`
+		TTL             string    `xml:"ttl"`
+		SkipHours       []string  `xml:"skipHours>hour"`
+		SkipDays        []string  `xml:"skipDays>day"`
+		UpdatePeriod    string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string    `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+		Base            string    `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+		Item            []RSSItem `xml:"item"`
+
+		LinkElements []xmlElement `xml:"link"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string         `xml:"title"`
+	Link        string         `xml:"-"`
+	Description string         `xml:"description"`
+	Content     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string         `xml:"pubDate"`
+	Author      string         `xml:"author"`
+	Creators    []string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string       `xml:"category"`
+	Comments    string         `xml:"-"`
+	GUID        string         `xml:"guid"`
+	Enclosures  []RSSEnclosure `xml:"enclosure"`
+	Base        string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+
+	ItunesDuration string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesImage    ItunesImage    `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	MediaContent   []MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup     []MediaContent `xml:"http://search.yahoo.com/mrss/ group>content"`
+
+	LinkElements     []xmlElement `xml:"link"`
+	CommentsElements []xmlElement `xml:"comments"`
+}
+
+// xmlElement is an element read together with its name. encoding/xml matches
+// a tag without a namespace in any namespace, so fields like <link> and
+// <comments> also pick up extensions such as <atom:link> and
+// <slash:comments> and need to be told apart.
+type xmlElement struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// unnamespacedValue returns the value of the first element that has no
+// namespace.
+func unnamespacedValue(elements []xmlElement) string {
+	for _, element := range elements {
+		if element.XMLName.Space == "" {
+			return element.Value
+		}
+	}
+	return ""
+}
+
+// RSSEnclosure is a media file attached to an item. Duration and Image are
+// filled in by the parsers from the itunes and media RSS extensions.
+type RSSEnclosure struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Length   string `xml:"length,attr"`
+	Duration string `xml:"-"`
+	Image    string `xml:"-"`
+}
+
+type ItunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type MediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+	Medium   string `xml:"medium,attr"`
+}
+
+// RDFFeed is an RSS 1.0 document, where items are siblings of the channel
+// instead of children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Item []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+type AtomFeed struct {
+	Base     string       `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title    AtomText     `xml:"title"`
+	Subtitle AtomText     `xml:"subtitle"`
+	Link     []AtomLink   `xml:"link"`
+	Author   []AtomPerson `xml:"author"`
+	Entry    []AtomEntry  `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Base      string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID        string         `xml:"id"`
+	Title     AtomText       `xml:"title"`
+	Link      []AtomLink     `xml:"link"`
+	Published string         `xml:"published"`
+	Updated   string         `xml:"updated"`
+	Summary   AtomText       `xml:"summary"`
+	Content   AtomText       `xml:"content"`
+	Author    []AtomPerson   `xml:"author"`
+	Category  []AtomCategory `xml:"category"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomText holds an Atom text construct. xhtml content arrives as child
+// elements, so its markup is kept from the inner xml instead of the chardata.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// HTML returns the text as markup, escaping plain text so that characters
+// like "<" and "&" are shown as written.
+func (t AtomText) HTML() string {
+	switch t.Type {
+	case "", "text", "text/plain":
+		return html.EscapeString(t.String())
+	}
+	return t.String()
+}
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            json.RawMessage      `json:"id"`
+	URL           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Author        *JSONFeedAuthor      `json:"author"` // JSON Feed 1.0
+	Tags          []string             `json:"tags"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// IDString coerces the item id to a string, as the spec asks readers to do
+// when a feed publishes numeric ids.
+func (i JSONFeedItem) IDString() string {
+	var id string
+	if err := json.Unmarshal(i.ID, &id); err == nil {
+		return id
+	}
+	return strings.TrimSpace(string(i.ID))
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
 }
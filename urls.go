@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/IlMeloIl/RSS/internal/database"
+)
+
+// trackingParams are query parameters that only identify where a link was
+// shared, never which feed it points to.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"igshid":  true,
+}
+
+// normalizeFeedURL cleans up a feed url without changing what it fetches:
+// scheme and host are lowercased and default ports and fragments dropped.
+// Credentials and the query are kept exactly as given.
+func normalizeFeedURL(rawURL string) (string, error) {
+	u, err := parseFeedURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// canonicalFeedURL is the identity of a feed url. On top of normalizeFeedURL
+// it ignores http vs https, a trailing slash, credentials and tracking
+// parameters, and sorts the query, so every spelling of the same feed maps
+// to one value. It is never fetched.
+func canonicalFeedURL(rawURL string) (string, error) {
+	u, err := parseFeedURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = "https"
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	u.User = nil
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func parseFeedURL(rawURL string) (*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", rawURL, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url %s: scheme must be http or https", rawURL)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid url %s: missing host", rawURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+
+	if u.Path != "" {
+		cleaned := path.Clean(u.Path)
+		if strings.HasSuffix(u.Path, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		u.Path = cleaned
+		u.RawPath = ""
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	return u, nil
+}
+
+// getFeedByAnyURL finds a feed by any spelling of its url. Feeds added
+// before canonical urls existed are still found by their stored url.
+func getFeedByAnyURL(ctx context.Context, s *state, rawURL string) (database.Feed, error) {
+	canonical, err := canonicalFeedURL(rawURL)
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	feed, err := s.db.GetFeedByCanonicalURL(ctx, sql.NullString{String: canonical, Valid: true})
+	if err != sql.ErrNoRows {
+		return feed, err
+	}
+
+	normalized, err := normalizeFeedURL(rawURL)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	feed, err = s.db.GetFeedByURL(ctx, normalized)
+	if err != sql.ErrNoRows || normalized == rawURL {
+		return feed, err
+	}
+	return s.db.GetFeedByURL(ctx, rawURL)
+}
+
+// htmlURLAttr matches quoted href and src attributes in HTML content.
+var htmlURLAttr = regexp.MustCompile(`(?i)(\s(?:href|src)\s*=\s*)("[^"]*"|'[^']*')`)
+
+// resolveItemURLs makes item links, enclosure urls and the hrefs and srcs in
+// descriptions absolute. Relative references resolve against the xml:base of
+// the item or channel, then the channel link, then the final fetch url. A
+// relative channel xml:base itself resolves against the fetch url.
+func resolveItemURLs(v *RSSFeed, fetchURL string) {
+	fetched, err := url.Parse(fetchURL)
+	if err != nil {
+		return
+	}
+	channelBase := fetched
+	if v.Channel.Base != "" {
+		channelBase = parseOr(fetched, resolveReference(fetched, v.Channel.Base))
+	}
+
+	base := fetched
+	if link := strings.TrimSpace(v.Channel.Link); link != "" {
+		v.Channel.Link = resolveReference(channelBase, link)
+		base = parseOr(base, v.Channel.Link)
+	}
+	if v.Channel.Base != "" {
+		base = channelBase
+	}
+
+	for i := range v.Channel.Item {
+		item := &v.Channel.Item[i]
+		itemBase := base
+		if item.Base != "" {
+			itemBase = parseOr(base, resolveReference(base, item.Base))
+		}
+
+		if link := strings.TrimSpace(item.Link); link != "" {
+			item.Link = resolveReference(itemBase, link)
+		}
+		if comments := strings.TrimSpace(item.Comments); comments != "" {
+			item.Comments = resolveReference(itemBase, comments)
+		}
+		for j := range item.Enclosures {
+			item.Enclosures[j].URL = resolveReference(itemBase, item.Enclosures[j].URL)
+			item.Enclosures[j].Image = resolveReference(itemBase, item.Enclosures[j].Image)
+		}
+		item.Description = resolveHTMLURLs(item.Description, itemBase)
+		item.Content = resolveHTMLURLs(item.Content, itemBase)
+	}
+}
+
+// resolveReference resolves ref against base, leaving absolute urls,
+// fragment-only links and unparsable values untouched.
+func resolveReference(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ref
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil || parsed.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+func parseOr(fallback *url.URL, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() {
+		return fallback
+	}
+	return u
+}
+
+func resolveHTMLURLs(fragment string, base *url.URL) string {
+	return htmlURLAttr.ReplaceAllStringFunc(fragment, func(attr string) string {
+		match := htmlURLAttr.FindStringSubmatch(attr)
+		quoted := match[2]
+		value := quoted[1 : len(quoted)-1]
+		return match[1] + quoted[:1] + html.EscapeString(resolveReference(base, html.UnescapeString(value))) + quoted[:1]
+	})
+}